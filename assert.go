@@ -0,0 +1,167 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	logHeaderRe     = regexp.MustCompile(`^\s+\S+\.go:\d+: ?$`)
+	testifyFieldRe  = regexp.MustCompile(`^\s+\t([A-Z][A-Za-z ]*):\s*\t(.*)$`)
+	testifyContRe   = regexp.MustCompile(`^\s+\t\s+\t(.*)$`)
+	cmpDiffHeaderRe = regexp.MustCompile(`\(-\w+ \+\w+\):?\s*$`)
+)
+
+// testifyField is a labeled field of a testify failure message, ie. "Error
+// Trace", "Error" or "Messages".
+type testifyField struct {
+	Label string
+	Lines []string
+}
+
+// renderTestify renders testify failure messages:
+//
+//	foo_test.go:12:
+//		Error Trace:	/src/foo_test.go:12
+//		Error:      	Not equal:
+//		            	expected: "a"
+//		            	actual  : "b"
+//		Test:       	TestFoo
+//
+// as the message, the location and a line diff of expected and actual.
+func renderTestify(flags Flags, es Events, textColor func(a ...interface{}) string) ([]OutputLine, int) {
+	if len(es) < 2 ||
+		!logHeaderRe.MatchString(strings.TrimSuffix(es[0].Output, "\n")) {
+		return nil, 0
+	}
+	m := testifyFieldRe.FindStringSubmatch(strings.TrimSuffix(es[1].Output, "\n"))
+	if m == nil || m[1] != "Error Trace" {
+		return nil, 0
+	}
+
+	var (
+		fields []testifyField
+		n      = 1
+	)
+	for _, e := range es[1:] {
+		line := strings.TrimSuffix(e.Output, "\n")
+		if e.Action != ActionOutput {
+			break
+		}
+		if m := testifyFieldRe.FindStringSubmatch(line); m != nil {
+			fields = append(fields, testifyField{Label: m[1], Lines: []string{m[2]}})
+		} else if m := testifyContRe.FindStringSubmatch(line); m != nil {
+			f := &fields[len(fields)-1]
+			f.Lines = append(f.Lines, m[1])
+		} else {
+			break
+		}
+		n++
+	}
+
+	var (
+		trace, message, messages []string
+		expected, actual         string
+		diff                     []string
+	)
+	for _, f := range fields {
+		switch f.Label {
+		case "Error Trace":
+			trace = f.Lines
+		case "Messages":
+			messages = f.Lines
+		case "Error":
+			inDiff := false
+			for _, l := range f.Lines {
+				switch {
+				case inDiff:
+					if strings.HasPrefix(l, "--- ") || strings.HasPrefix(l, "+++ ") {
+						continue
+					}
+					diff = append(diff, l)
+				case strings.TrimSpace(l) == "Diff:":
+					inDiff = true
+				case strings.HasPrefix(l, "expected: "):
+					expected = strings.TrimPrefix(l, "expected: ")
+				case strings.HasPrefix(l, "actual  : "):
+					actual = strings.TrimPrefix(l, "actual  : ")
+				case strings.TrimSpace(l) != "":
+					message = append(message, strings.TrimRight(l, " "))
+				}
+			}
+		}
+	}
+
+	header := strings.TrimRight(strings.TrimSuffix(es[0].Output, "\n"), " ")
+	indent := leadingSpace(header) + "    "
+	width := flags.truncateWidth()
+	var lines []OutputLine
+	add := func(text string) {
+		lines = append(lines, OutputLine{Event: es[0], Text: text})
+	}
+
+	if len(message) > 0 {
+		add(header + " " + textColor(message[0]))
+		for _, l := range message[1:] {
+			add(indent + textColor(TruncateMiddle(l, width)))
+		}
+	} else {
+		add(header)
+	}
+	for _, l := range messages {
+		add(indent + textColor(l))
+	}
+	if len(trace) > 0 {
+		add(indent + "at " + strings.Join(trace, ", "))
+	}
+	switch {
+	case len(diff) > 0:
+		for _, l := range diff {
+			add(indent + ColorDiffLine(TruncateMiddle(l, width)))
+		}
+	case expected != "" || actual != "":
+		for _, l := range LineDiff(splitValue(expected), splitValue(actual)) {
+			add(indent + DiffLine{Op: l.Op, Text: TruncateMiddle(l.Text, width)}.String())
+		}
+	}
+	return lines, n
+}
+
+// splitValue splits a value printed by testify into lines. Quoted strings are
+// unquoted first so that strings that differ in a line are diffed by line.
+func splitValue(value string) []string {
+	if s, err := strconv.Unquote(value); err == nil {
+		value = s
+	}
+	return strings.Split(value, "\n")
+}
+
+// renderCmpDiff colors cmp.Diff output following a message ending in
+// "(-want +got):" by line.
+func renderCmpDiff(flags Flags, es Events, textColor func(a ...interface{}) string) ([]OutputLine, int) {
+	header := strings.TrimSuffix(es[0].Output, "\n")
+	if es[0].Action != ActionOutput || !cmpDiffHeaderRe.MatchString(header) {
+		return nil, 0
+	}
+	indent := leadingSpace(header) + "    "
+	width := flags.truncateWidth()
+	lines := []OutputLine{{Event: es[0], Text: textColor(header)}}
+	n := 1
+	for _, e := range es[1:] {
+		line := strings.TrimSuffix(e.Output, "\n")
+		if e.Action != ActionOutput || !strings.HasPrefix(line, indent) {
+			break
+		}
+		line = strings.TrimPrefix(line, indent)
+		lines = append(lines, OutputLine{
+			Event: e,
+			Text:  indent + ColorDiffLine(TruncateMiddle(line, width)),
+		})
+		n++
+	}
+	if n == 1 {
+		return nil, 0
+	}
+	return lines, n
+}
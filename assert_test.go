@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// renderedText returns the text of lines.
+func renderedText(lines []OutputLine) []string {
+	var text []string
+	for _, l := range lines {
+		text = append(text, l.Text)
+	}
+	return text
+}
+
+func TestRenderTestify(t *testing.T) {
+	key := Key{Package: "example.com/ex", Test: "TestX"}
+	for _, tc := range []struct {
+		name   string
+		output []string
+		want   []string
+		n      int
+	}{
+		{
+			name:   "not testify",
+			output: []string{"    x_test.go:12: got 1, want 2"},
+		},
+		{
+			name: "not equal",
+			output: []string{
+				"    x_test.go:12: ",
+				"        \tError Trace:\t/src/x_test.go:12",
+				"        \tError:      \tNot equal: ",
+				"        \t            \texpected: 1",
+				"        \t            \tactual  : 2",
+				"        \tTest:       \tTestX",
+				"--- FAIL: TestX (0.00s)",
+			},
+			want: []string{
+				"    x_test.go:12: Not equal:",
+				"        at /src/x_test.go:12",
+				"        - 1",
+				"        + 2",
+			},
+			n: 6,
+		},
+		{
+			name: "multi line strings",
+			output: []string{
+				"    x_test.go:12: ",
+				"        \tError Trace:\t/src/x_test.go:12",
+				"        \tError:      \tNot equal: ",
+				"        \t            \texpected: \"a\\nb\\nc\"",
+				"        \t            \tactual  : \"a\\nB\\nc\"",
+				"        \tTest:       \tTestX",
+				"        \tMessages:   \tlines",
+			},
+			want: []string{
+				"    x_test.go:12: Not equal:",
+				"        lines",
+				"        at /src/x_test.go:12",
+				"          a",
+				"        - b",
+				"        + B",
+				"          c",
+			},
+			n: 7,
+		},
+		{
+			name: "diff",
+			output: []string{
+				"    x_test.go:12: ",
+				"        \tError Trace:\t/src/x_test.go:12",
+				"        \tError:      \tNot equal: ",
+				"        \t            \texpected: []int{1, 2}",
+				"        \t            \tactual  : []int{1, 3}",
+				"        \t            \t",
+				"        \t            \tDiff:",
+				"        \t            \t--- Expected",
+				"        \t            \t+++ Actual",
+				"        \t            \t@@ -2,2 +2,2 @@",
+				"        \t            \t- 2",
+				"        \t            \t+ 3",
+				"        \tTest:       \tTestX",
+			},
+			want: []string{
+				"    x_test.go:12: Not equal:",
+				"        at /src/x_test.go:12",
+				"        @@ -2,2 +2,2 @@",
+				"        - 2",
+				"        + 3",
+			},
+			n: 13,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lines, n := renderTestify(Flags{}, outputEvents(key, tc.output...), fmt.Sprint)
+			if n != tc.n {
+				t.Errorf("consumed %d events, want %d", n, tc.n)
+			}
+			if got := renderedText(lines); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRenderCmpDiff(t *testing.T) {
+	key := Key{Package: "example.com/ex", Test: "TestX"}
+	for _, tc := range []struct {
+		name   string
+		output []string
+		want   []string
+		n      int
+	}{
+		{
+			name:   "no diff header",
+			output: []string{"    x_test.go:12: got 1, want 2"},
+		},
+		{
+			name:   "header only",
+			output: []string{"    x_test.go:12: mismatch (-want +got):", "--- FAIL: TestX (0.00s)"},
+		},
+		{
+			name: "diff",
+			output: []string{
+				"    x_test.go:12: mismatch (-want +got):",
+				"          main.T{",
+				"        - \tA: 1,",
+				"        + \tA: 2,",
+				"          }",
+				"--- FAIL: TestX (0.00s)",
+			},
+			want: []string{
+				"    x_test.go:12: mismatch (-want +got):",
+				"          main.T{",
+				"        - \tA: 1,",
+				"        + \tA: 2,",
+				"          }",
+			},
+			n: 5,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lines, n := renderCmpDiff(Flags{}, outputEvents(key, tc.output...), fmt.Sprint)
+			if n != tc.n {
+				t.Errorf("consumed %d events, want %d", n, tc.n)
+			}
+			if got := renderedText(lines); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
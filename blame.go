@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Blame is the last change of a line.
+type Blame struct {
+	Commit string
+	Author string
+	Time   time.Time
+}
+
+// IsCommitted reports whether the line is committed.
+func (b Blame) IsCommitted() bool {
+	return strings.Trim(b.Commit, "0") != ""
+}
+
+func (b Blame) String() string {
+	if !b.IsCommitted() {
+		return "not committed yet"
+	}
+	commit := b.Commit
+	if len(commit) > 8 {
+		commit = commit[:8]
+	}
+	return commit + " " + b.Author + " " + b.Time.Format("2006-01-02")
+}
+
+// BlameCache caches git blame per file.
+type BlameCache struct {
+	ctx     context.Context
+	sources *SourceCache
+	files   map[string]map[int]Blame
+}
+
+func NewBlameCache(ctx context.Context, sources *SourceCache) *BlameCache {
+	return &BlameCache{ctx: ctx, sources: sources, files: make(map[string]map[int]Blame)}
+}
+
+// Line returns the blame of line in filename. It is false if filename is not
+// in a git checkout.
+func (c *BlameCache) Line(filename string, line int) (Blame, bool) {
+	lines, ok := c.files[filename]
+	if !ok {
+		lines = c.blame(filename)
+		// remember failures too so git only runs once per file
+		c.files[filename] = lines
+	}
+	b, ok := lines[line]
+	return b, ok
+}
+
+// blame runs git blame on filename, nil if it fails.
+func (c *BlameCache) blame(filename string) map[int]Blame {
+	cmd := exec.CommandContext(c.ctx, "git", "blame", "--line-porcelain", "--", filepath.Base(filename))
+	cmd.Dir = filepath.Dir(filename)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return parseBlame(bytes.NewReader(out))
+}
+
+// parseBlame parses the output of git blame --line-porcelain by line number.
+func parseBlame(r io.Reader) map[int]Blame {
+	lines := make(map[int]Blame)
+	var (
+		b    Blame
+		line int
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			lines[line] = b
+			b, line = Blame{}, 0
+		case line == 0:
+			// <commit> <original line> <final line> [<lines in group>]
+			fields := strings.Fields(text)
+			if len(fields) >= 3 {
+				b.Commit = fields[0]
+				line, _ = strconv.Atoi(fields[2])
+			}
+		case strings.HasPrefix(text, "author "):
+			b.Author = strings.TrimPrefix(text, "author ")
+		case strings.HasPrefix(text, "author-time "):
+			sec, _ := strconv.ParseInt(strings.TrimPrefix(text, "author-time "), 10, 64)
+			b.Time = time.Unix(sec, 0)
+		}
+	}
+	return lines
+}
+
+// AddBlame appends the last change of the referenced line to the output
+// lines with file:line references and to the first frame in modules of the
+// panic in es.
+func (c *BlameCache) AddBlame(modules []string, es Events, lines []OutputLine) []OutputLine {
+	if c == nil {
+		return lines
+	}
+	var frame *Frame
+	if p := es.FindPanic(); p != nil && len(p.Goroutines) > 0 {
+		if frames := p.Goroutines[0].ModuleFrames(modules); len(frames) > 0 {
+			frame = &frames[0]
+		}
+	}
+	for i, line := range lines {
+		// snippets share the event of the line that referenced them
+		if i > 0 && lines[i-1].Event == line.Event {
+			continue
+		}
+		var (
+			path string
+			n    int
+		)
+		if ref, ok := ParseFileRef(line.Event.Output); ok {
+			path, n = c.sources.Resolve(line.Event.Package, ref.File), ref.Line
+		} else if frame != nil && line.Event.Output == "    "+frame.String()+"\n" {
+			// as printed by CollapseStacks
+			path, n = frame.File, frame.Line
+			frame = nil
+		}
+		if path == "" {
+			continue
+		}
+		if b, ok := c.Line(path, n); ok {
+			lines[i].Text += "  " + timeColor("["+b.String()+"]")
+		}
+	}
+	return lines
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const blamePorcelain = `0123456789abcdef0123456789abcdef01234567 1 1 2
+author Ada Lovelace
+author-mail <ada@example.com>
+author-time 1700000000
+author-tz +0000
+summary add x
+filename x_test.go
+	package x
+0123456789abcdef0123456789abcdef01234567 2 2
+author Ada Lovelace
+author-time 1700000000
+filename x_test.go
+	
+0000000000000000000000000000000000000000 3 3 1
+author Not Committed Yet
+author-time 1700000100
+filename x_test.go
+	func TestX(t *testing.T) {}
+`
+
+func TestParseBlame(t *testing.T) {
+	lines := parseBlame(strings.NewReader(blamePorcelain))
+	ada := Blame{Commit: "0123456789abcdef0123456789abcdef01234567", Author: "Ada Lovelace", Time: time.Unix(1700000000, 0)}
+	want := map[int]Blame{
+		1: ada,
+		2: ada,
+		3: {Commit: "0000000000000000000000000000000000000000", Author: "Not Committed Yet", Time: time.Unix(1700000100, 0)},
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("got %+v, want %+v", lines, want)
+	}
+	if got, want := lines[1].String(), "01234567 Ada Lovelace "+ada.Time.Format("2006-01-02"); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got := lines[3].String(); got != "not committed yet" {
+		t.Errorf("String() = %q, want not committed yet", got)
+	}
+}
+
+func TestAddBlame(t *testing.T) {
+	c := NewBlameCache(context.Background(), NewSourceCache(context.Background(), "go"))
+	c.files["/src/x_test.go"] = parseBlame(strings.NewReader(blamePorcelain))
+	key := Key{Package: "example.com/x", Test: "TestX"}
+	es := outputEvents(key, "    /src/x_test.go:1: failed", "    /src/x_test.go:9: no blame", "plain")
+	lines := []OutputLine{
+		{Event: es[0], Text: "    /src/x_test.go:1: failed"},
+		{Event: es[0], Text: "        > 1 | package x"},
+		{Event: es[1], Text: "    /src/x_test.go:9: no blame"},
+		{Event: es[2], Text: "plain"},
+	}
+	date := time.Unix(1700000000, 0).Format("2006-01-02")
+	want := []string{
+		"    /src/x_test.go:1: failed  [01234567 Ada Lovelace " + date + "]",
+		"        > 1 | package x",
+		"    /src/x_test.go:9: no blame",
+		"plain",
+	}
+	if got := renderedText(c.AddBlame(nil, es, lines)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
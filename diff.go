@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var (
+	diffDelColor  = color.New(color.FgRed).SprintFunc()
+	diffAddColor  = color.New(color.FgGreen).SprintFunc()
+	diffHunkColor = color.New(color.FgCyan).SprintFunc()
+)
+
+// DiffOp is the kind of a line in a line diff.
+type DiffOp byte
+
+const (
+	DiffEqual DiffOp = ' '
+	DiffDel   DiffOp = '-'
+	DiffAdd   DiffOp = '+'
+)
+
+// DiffLine is a line in a line diff.
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// maxDiffLines limits the size of inputs diffed line by line, larger inputs
+// are shown as a removal of a followed by an addition of b.
+const maxDiffLines = 2000
+
+// LineDiff returns a line diff turning a into b, based on their longest common
+// subsequence.
+func LineDiff(a, b []string) []DiffLine {
+	if len(a)*len(b) > maxDiffLines*maxDiffLines {
+		var lines []DiffLine
+		for _, l := range a {
+			lines = append(lines, DiffLine{DiffDel, l})
+		}
+		for _, l := range b {
+			lines = append(lines, DiffLine{DiffAdd, l})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{DiffEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{DiffDel, a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{DiffAdd, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{DiffDel, a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{DiffAdd, b[j]})
+	}
+	return lines
+}
+
+// ColorDiffLine colors a line of a unified diff by its first character.
+func ColorDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "@@"):
+		return diffHunkColor(line)
+	case strings.HasPrefix(line, "-"):
+		return diffDelColor(line)
+	case strings.HasPrefix(line, "+"):
+		return diffAddColor(line)
+	}
+	return line
+}
+
+// String formats l as a colored diff line.
+func (l DiffLine) String() string {
+	return ColorDiffLine(string(l.Op) + " " + l.Text)
+}
+
+// TruncateMiddle shortens s to about width characters by cutting out the
+// middle. A width of 0 or less disables truncation.
+func TruncateMiddle(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	head := width / 2
+	tail := width - head
+	return string(r[:head]) +
+		fmt.Sprintf(" …%d chars… ", len(r)-head-tail) +
+		string(r[len(r)-tail:])
+}
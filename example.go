@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsExample reports whether key is an example function.
+func (t Key) IsExample() bool {
+	return strings.HasPrefix(t.Test, "Example")
+}
+
+// FindExampleOutput returns the got and want blocks printed by a failing
+// example.
+func (es Events) FindExampleOutput() (got, want []string, ok bool) {
+	if len(es) == 0 || !es[0].Key().IsExample() {
+		return nil, nil, false
+	}
+	var block *[]string
+	for _, line := range es.outputLines() {
+		switch {
+		case line == "got:":
+			block, ok = &got, true
+		case line == "want:" && ok:
+			block = &want
+		case strings.HasPrefix(line, "--- "):
+			block = nil
+		case block != nil:
+			*block = append(*block, line)
+		}
+	}
+	return got, want, ok
+}
+
+// visibleWhitespace makes spaces and tabs in s visible.
+func visibleWhitespace(s string) string {
+	s = strings.ReplaceAll(s, " ", "·")
+	s = strings.ReplaceAll(s, "\t", "→")
+	if s == "" {
+		s = "⏎"
+	}
+	return s
+}
+
+// renderExample renders the got: and want: blocks of a failing example as a
+// diff with visible white space.
+func renderExample(flags Flags, es Events, textColor func(a ...interface{}) string) ([]OutputLine, int) {
+	if es[0].Action != ActionOutput || !es[0].Key().IsExample() || es[0].Output != "got:\n" {
+		return nil, 0
+	}
+	var got, want []string
+	block := &got
+	n := 1
+	for _, e := range es[1:] {
+		if e.Action != ActionOutput {
+			break
+		}
+		line := strings.TrimSuffix(e.Output, "\n")
+		if line == "want:" && block == &got {
+			block = &want
+		} else {
+			*block = append(*block, line)
+		}
+		n++
+	}
+	if block != &want {
+		return nil, 0
+	}
+
+	lines := []OutputLine{{Event: es[0], Text: textColor("example output mismatch (-want +got):")}}
+	for _, l := range LineDiff(want, got) {
+		text := l.Text
+		if l.Op != DiffEqual {
+			text = visibleWhitespace(text)
+		}
+		lines = append(lines, OutputLine{
+			Event: es[0],
+			Text:  "    " + DiffLine{Op: l.Op, Text: text}.String(),
+		})
+	}
+	if !flags.UpdateExamples {
+		lines = append(lines, OutputLine{
+			Event: es[0],
+			Text:  "    " + timeColor("run with TGO_UPDATE_EXAMPLES=1 to update the // Output: comment"),
+		})
+	}
+	return lines, n
+}
+
+// UpdateExamples replaces the // Output: comments of the failing examples in
+// ts with the output they printed.
+func (ts TestStorage) UpdateExamples(ctx context.Context, flags Flags) error {
+	examples := ts.subset()
+	for key, events := range ts.FindByStatus(StatusFail).Tests {
+		if _, _, ok := events.FindExampleOutput(); ok {
+			examples.Tests[key] = events
+		}
+	}
+	if len(examples.Tests) == 0 {
+		return nil
+	}
+	packages, err := ListPackages(ctx, flags.Bin, examples.Packages())
+	if err != nil && len(packages) == 0 {
+		return err
+	}
+	for _, key := range examples.OrderedKeys() {
+		got, _, _ := examples.Tests[key].FindExampleOutput()
+		p, ok := packages[key.Package]
+		if !ok {
+			fmt.Printf("could not find the directory of %s\n", key.Package)
+			continue
+		}
+		var files []string
+		files = append(files, p.TestGoFiles...)
+		files = append(files, p.XTestGoFiles...)
+		updated := false
+		for _, name := range files {
+			filename := filepath.Join(p.Dir, name)
+			ok, err := updateExampleOutput(filename, key.Test, got)
+			if err != nil {
+				return err
+			}
+			if ok {
+				fmt.Printf("updated the output of %s in %s\n", testColor(key.Test), filename)
+				updated = true
+				break
+			}
+		}
+		if !updated {
+			fmt.Printf("could not find the output comment of %s\n", key)
+		}
+	}
+	return nil
+}
+
+// updateExampleOutput replaces the output comment of the example function
+// name in filename with got. It reports whether the function was found.
+func updateExampleOutput(filename, name string, got []string) (bool, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return false, err
+	}
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return false, err
+	}
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Name.Name != name || fn.Body == nil {
+			continue
+		}
+		// like go/doc the output comment is the last comment in the body
+		var last *ast.CommentGroup
+		for _, cg := range f.Comments {
+			if cg.Pos() > fn.Body.Lbrace && cg.End() < fn.Body.Rbrace {
+				last = cg
+			}
+		}
+		if last == nil {
+			return false, nil
+		}
+		text := last.Text()
+		var label string
+		for _, l := range []string{"Output:", "output:", "Unordered output:", "unordered output:"} {
+			if strings.HasPrefix(text, l) {
+				label = l
+				break
+			}
+		}
+		if label == "" {
+			return false, nil
+		}
+
+		start := fset.Position(last.Pos()).Offset
+		end := fset.Position(last.End()).Offset
+		lineStart := bytes.LastIndexByte(src[:start], '\n') + 1
+		indent := string(src[lineStart:start])
+
+		var sb strings.Builder
+		sb.WriteString("// " + label)
+		for _, l := range got {
+			sb.WriteString("\n" + indent + strings.TrimRight("// "+l, " "))
+		}
+
+		var out []byte
+		out = append(out, src[:start]...)
+		out = append(out, sb.String()...)
+		out = append(out, src[end:]...)
+		return true, os.WriteFile(filename, out, info.Mode().Perm())
+	}
+	return false, nil
+}
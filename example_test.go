@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var exampleOutput = []string{
+	"=== RUN   Example",
+	"--- FAIL: Example (0.00s)",
+	"got:",
+	"hello",
+	"world ",
+	"want:",
+	"hello",
+	"world",
+	"!",
+}
+
+func TestFindExampleOutput(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		key       Key
+		output    []string
+		got, want []string
+		ok        bool
+	}{
+		{
+			name:   "example",
+			key:    Key{Package: "example.com/ex", Test: "Example"},
+			output: exampleOutput,
+			got:    []string{"hello", "world "},
+			want:   []string{"hello", "world", "!"},
+			ok:     true,
+		},
+		{
+			name:   "not an example",
+			key:    Key{Package: "example.com/ex", Test: "TestX"},
+			output: exampleOutput,
+		},
+		{
+			name:   "passing example",
+			key:    Key{Package: "example.com/ex", Test: "Example"},
+			output: []string{"=== RUN   Example", "--- PASS: Example (0.00s)"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, want, ok := outputEvents(tc.key, tc.output...).FindExampleOutput()
+			if ok != tc.ok || !reflect.DeepEqual(got, tc.got) || !reflect.DeepEqual(want, tc.want) {
+				t.Errorf("got %q, %q, %v, want %q, %q, %v", got, want, ok, tc.got, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestRenderExample(t *testing.T) {
+	key := Key{Package: "example.com/ex", Test: "Example"}
+	es := outputEvents(key, exampleOutput...)[2:]
+	lines, n := renderExample(Flags{UpdateExamples: true}, es, fmt.Sprint)
+	if n != len(es) {
+		t.Errorf("consumed %d events, want %d", n, len(es))
+	}
+	want := []string{
+		"example output mismatch (-want +got):",
+		"      hello",
+		"    - world",
+		"    - !",
+		"    + world·",
+	}
+	if got := renderedText(lines); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUpdateExampleOutput(t *testing.T) {
+	src := `package ex
+
+import "fmt"
+
+func Example() {
+	fmt.Println("hello")
+	// Output:
+	// hi
+}
+
+func ExampleOther() {
+	fmt.Println("other")
+	// Output: other
+}
+`
+	want := `package ex
+
+import "fmt"
+
+func Example() {
+	fmt.Println("hello")
+	// Output:
+	// hello
+	//
+	// world
+}
+
+func ExampleOther() {
+	fmt.Println("other")
+	// Output: other
+}
+`
+	filename := filepath.Join(t.TempDir(), "ex_test.go")
+	if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := updateExampleOutput(filename, "ExampleMissing", nil); ok || err != nil {
+		t.Errorf("ExampleMissing: got %v, %v, want false, nil", ok, err)
+	}
+	ok, err := updateExampleOutput(filename, "Example", []string{"hello", "", "world"})
+	if !ok || err != nil {
+		t.Fatalf("got %v, %v, want true, nil", ok, err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
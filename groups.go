@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FailureMessage returns the message a failing test failed with: the panic
+// value, the t.Error output when go test marks it or else all of the test's
+// own output, without file:line prefixes.
+func (es Events) FailureMessage() string {
+	if p := es.FindPanic(); p != nil {
+		return "panic: " + p.Value
+	}
+	var errs, all []string
+	for _, e := range es.Compact() {
+		if e.Action != ActionOutput {
+			continue
+		}
+		output := strings.TrimSpace(e.Output)
+		if output == "" || strings.HasPrefix(output, "--- ") {
+			continue
+		}
+		output = strings.TrimSpace(fileRefRe.ReplaceAllString(output, ""))
+		if strings.HasPrefix(e.OutputType, "error") {
+			errs = append(errs, output)
+		}
+		all = append(all, output)
+	}
+	if len(errs) > 0 {
+		return strings.Join(errs, "\n")
+	}
+	return strings.Join(all, "\n")
+}
+
+var fingerprintReplacer = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`0x[0-9a-fA-F]+`), "0x…"},
+	{regexp.MustCompile(`(?:/tmp|/var/folders|/private/var/folders|(?i:[a-z]:\\[^\s]*\\Temp))[^\s:"')]*`), "<tmp>"},
+	{regexp.MustCompile(`\b(?:\d+(?:\.\d+)?(?:ns|µs|us|ms|s|m|h))+\b`), "<dur>"},
+	{regexp.MustCompile(`\d+`), "N"},
+}
+
+// FailureFingerprint normalises a failure message so that failures differing
+// only in numbers, addresses, temporary paths and durations compare equal.
+func FailureFingerprint(message string) string {
+	for _, r := range fingerprintReplacer {
+		message = r.re.ReplaceAllString(message, r.repl)
+	}
+	return message
+}
+
+// FailureGroup is a set of tests that failed with the same normalised
+// message.
+type FailureGroup struct {
+	Fingerprint string
+	Message     string // the message of the first test in the group
+	Keys        []Key
+}
+
+// FailureGroups groups the tests in ts by the fingerprint of their failure
+// message, largest groups first.
+func (ts TestStorage) FailureGroups() []*FailureGroup {
+	var groups []*FailureGroup
+	byFingerprint := make(map[string]*FailureGroup)
+	for _, key := range ts.OrderedKeys() {
+		if key.Test == "" {
+			continue
+		}
+		message := ts.Tests[key].FailureMessage()
+		if message == "" {
+			continue
+		}
+		fp := FailureFingerprint(message)
+		g, ok := byFingerprint[fp]
+		if !ok {
+			g = &FailureGroup{Fingerprint: fp, Message: message}
+			byFingerprint[fp] = g
+			groups = append(groups, g)
+		}
+		g.Keys = append(g.Keys, key)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Keys) > len(groups[j].Keys)
+	})
+	return groups
+}
+
+// PrintFailureGroups prints the groups of failing tests in ts that share
+// their failure message with at least one other test.
+func (ts TestStorage) PrintFailureGroups(flags Flags) {
+	var groups []*FailureGroup
+	for _, g := range ts.FailureGroups() {
+		if len(g.Keys) > 1 {
+			groups = append(groups, g)
+		}
+	}
+	if len(groups) == 0 {
+		return
+	}
+
+	maxLines, maxKeys := 5, 10
+	if flags.V >= V2 {
+		maxLines, maxKeys = 20, 0
+	}
+
+	hr := failColor("════════════")
+	fmt.Println(hr, failColorBold("FAILURE GROUPS"), hr)
+	for _, g := range groups {
+		fmt.Println(failColorBold(fmt.Sprintf("%6d ", len(g.Keys))) + failColor("tests failed with:"))
+		lines := strings.Split(g.Message, "\n")
+		for i, line := range lines {
+			if i == maxLines {
+				fmt.Printf("         … %d more lines\n", len(lines)-maxLines)
+				break
+			}
+			fmt.Println("         " + TruncateMiddle(line, flags.truncateWidth()))
+		}
+		for i, key := range g.Keys {
+			if maxKeys > 0 && i == maxKeys {
+				fmt.Printf("       … and %d more\n", len(g.Keys)-maxKeys)
+				break
+			}
+			fmt.Println("       " + ts.Links.LinkPackage(key.Package, packageColor(key.Package)) +
+				"." + ts.Links.LinkTest(key, testColor(key.Test)))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFailureMessage(t *testing.T) {
+	key := Key{Package: "example.com/ex", Test: "TestX"}
+	for _, tc := range []struct {
+		name   string
+		events Events
+		want   string
+	}{
+		{
+			name: "output",
+			events: outputEvents(key,
+				"=== RUN   TestX",
+				"    x_test.go:12: got 1",
+				"        want 2",
+				"--- FAIL: TestX (0.00s)",
+			),
+			want: "got 1\nwant 2",
+		},
+		{
+			name: "error typed",
+			events: Events{
+				{Action: ActionOutput, Package: key.Package, Test: key.Test, Output: "    x_test.go:10: setup\n"},
+				{Action: ActionOutput, Package: key.Package, Test: key.Test, Output: "    x_test.go:12: got 1\n", OutputType: "error"},
+				{Action: ActionOutput, Package: key.Package, Test: key.Test, Output: "        want 2\n", OutputType: "error-continue"},
+			},
+			want: "got 1\nwant 2",
+		},
+		{
+			name:   "panic",
+			events: outputEvents(key, "panic: boom [recovered]", "\tpanic: boom", "", "goroutine 7 [running]:"),
+			want:   "panic: boom",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.events.FailureMessage(); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFailureFingerprint(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		same bool
+	}{
+		{"got 1, want 2", "got 3, want 4", true},
+		{"pointer 0xc000123456", "pointer 0xc000abcdef", true},
+		{"open /tmp/TestX123/001/f: no such file", "open /tmp/TestX456/002/f: no such file", true},
+		{"timed out after 1.5s", "timed out after 200ms", true},
+		{"got 1, want 2", "got 1, wanted 2", false},
+	} {
+		if same := FailureFingerprint(tc.a) == FailureFingerprint(tc.b); same != tc.same {
+			t.Errorf("%q and %q: same = %v, want %v", tc.a, tc.b, same, tc.same)
+		}
+	}
+}
+
+func TestFailureGroups(t *testing.T) {
+	tests := NewTestStorage(nil)
+	pkg := "example.com/ex"
+	for test, output := range map[string]string{
+		"TestA": "    x_test.go:10: got 1, want 2",
+		"TestB": "    x_test.go:20: got 3, want 4",
+		"TestC": "    x_test.go:30: something else",
+	} {
+		for _, e := range outputEvents(Key{Package: pkg, Test: test}, output) {
+			tests.Append(e)
+		}
+	}
+	groups := tests.FailureGroups()
+	var keys [][]string
+	for _, g := range groups {
+		var tests []string
+		for _, key := range g.Keys {
+			tests = append(tests, key.Test)
+		}
+		keys = append(keys, tests)
+	}
+	if want := [][]string{{"TestA", "TestB"}, {"TestC"}}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got %v, want %v", keys, want)
+	}
+	if got, want := groups[0].Message, "got 1, want 2"; got != want {
+		t.Errorf("message %q, want %q", got, want)
+	}
+}
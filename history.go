@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryRun is the record of a run kept in the history.
+type HistoryRun struct {
+	Time   time.Time
+	Commit string `json:",omitempty"`
+	Branch string `json:",omitempty"`
+	Args   []string
+	Flags  Flags
+	Tests  []HistoryTest
+}
+
+// HistoryTest is the result of a single key in a HistoryRun.
+type HistoryTest struct {
+	Package     string
+	Test        string `json:",omitempty"`
+	Status      Status
+	Elapsed     float64
+	Fingerprint string `json:",omitempty"`
+	Coverage    string `json:",omitempty"`
+}
+
+// Key returns the key of t.
+func (t HistoryTest) Key() Key {
+	return Key{Package: t.Package, Test: t.Test}
+}
+
+// Results returns the tests of r by key.
+func (r HistoryRun) Results() map[Key]HistoryTest {
+	results := make(map[Key]HistoryTest, len(r.Tests))
+	for _, t := range r.Tests {
+		results[t.Key()] = t
+	}
+	return results
+}
+
+// HistoryRun returns the results in ts as a HistoryRun.
+func (ts TestStorage) HistoryRun(flags Flags, argv []string) HistoryRun {
+	run := HistoryRun{
+		Time:  time.Now(),
+		Args:  ts.Redactor.RedactArgs(argv),
+		Flags: ts.Redactor.RedactFlags(flags),
+	}
+	for _, key := range ts.OrderedKeys() {
+		events := ts.Tests[key]
+		t := HistoryTest{
+			Package:  key.Package,
+			Test:     key.Test,
+			Status:   ts.StatusOf(key),
+			Coverage: events.FindCoverage(),
+		}
+		if e := events.FindFirstByAction(EndingActions...); e != nil {
+			t.Elapsed = e.Elapsed
+		}
+		if FailureStatuses.Any(t.Status) {
+			t.Fingerprint = FailureFingerprint(events.FailureMessage())
+		}
+		run.Tests = append(run.Tests, t)
+	}
+	return run
+}
+
+// SaveHistory adds the results in ts to the history of its modules. The
+// failure history is loaded first, or the run saved would be taken for a
+// previous run when tagging its own failures.
+func (ts TestStorage) SaveHistory(ctx context.Context, flags Flags, argv []string) error {
+	ts.FailureHistory.HasRuns()
+	history, err := OpenHistory(ts.Modules)
+	if err != nil {
+		return err
+	}
+	record := ts.HistoryRun(flags, argv)
+	record.Commit, record.Branch = gitHead(ctx)
+	return history.Save(record, flags.HistoryKeep)
+}
+
+// History is the store of previous runs of a module, one JSON file per run.
+type History struct {
+	Dir string
+}
+
+var historyNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// HistoryDir returns the directory the history of module is kept in,
+// $XDG_CACHE_HOME/tgo/<module> or the platform's equivalent.
+func HistoryDir(module string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, "tgo", historyNameRe.ReplaceAllString(module, "_")), nil
+}
+
+// OpenHistory returns the history of the first of the main modules, or of the
+// current directory outside a module.
+func OpenHistory(modules []string) (*History, error) {
+	module := ""
+	if len(modules) > 0 {
+		module = modules[0]
+	} else if wd, err := os.Getwd(); err == nil {
+		module = wd
+	}
+	dir, err := HistoryDir(module)
+	if err != nil {
+		return nil, err
+	}
+	return &History{Dir: dir}, nil
+}
+
+// Save adds run to the history and removes all but the newest keep runs, keep
+// <= 0 keeps all runs.
+func (h *History) Save(run HistoryRun, keep int) error {
+	if err := os.MkdirAll(h.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	name := run.Time.UTC().Format("20060102T150405.000000000Z") + ".json"
+	if err := os.WriteFile(filepath.Join(h.Dir, name), data, 0o644); err != nil {
+		return err
+	}
+	if keep <= 0 {
+		return nil
+	}
+	files, err := h.files()
+	if err != nil {
+		return err
+	}
+	for _, f := range files[min(keep, len(files)):] {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// files returns the run files in the history, newest first.
+func (h *History) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(h.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// the names sort by time
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+// Runs returns the runs in the history, newest first. Unreadable runs are
+// skipped.
+func (h *History) Runs() ([]HistoryRun, error) {
+	files, err := h.files()
+	if err != nil {
+		return nil, err
+	}
+	var runs []HistoryRun
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var run HistoryRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// gitOutput runs git with args and returns its trimmed output, "" if it fails.
+func gitOutput(ctx context.Context, args ...string) string {
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// gitHead returns the commit and branch of the checkout in the current
+// directory, both "" outside git.
+func gitHead(ctx context.Context) (commit, branch string) {
+	return gitOutput(ctx, "rev-parse", "HEAD"), gitOutput(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// gitMergeBase returns the merge base of HEAD and the default branch, "" if
+// there is none.
+func gitMergeBase(ctx context.Context) string {
+	for _, ref := range []string{"origin/HEAD", "origin/main", "origin/master", "main", "master"} {
+		if base := gitOutput(ctx, "merge-base", "HEAD", ref); base != "" {
+			return base
+		}
+	}
+	return ""
+}
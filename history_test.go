@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryRun(t *testing.T) {
+	tests := loadTests(t, "cascade.json")
+	run := tests.HistoryRun(Flags{}, []string{"./..."})
+	pkg := "example.com/ex/cascade"
+	results := run.Results()
+	for key, want := range map[Key]Status{
+		{Package: pkg}:                        StatusFail,
+		{Package: pkg, Test: "TestParent"}:    StatusFail,
+		{Package: pkg, Test: "TestParent/ok"}: StatusPass,
+	} {
+		if got := results[key].Status; got != want {
+			t.Errorf("%s: %s, want %s", key, got, want)
+		}
+	}
+	if results[Key{Package: pkg, Test: "TestOwn/bad"}].Fingerprint == "" {
+		t.Error("failure without fingerprint")
+	}
+	if results[Key{Package: pkg, Test: "TestParent/ok"}].Fingerprint != "" {
+		t.Error("pass with fingerprint")
+	}
+}
+
+func TestHistorySave(t *testing.T) {
+	h := &History{Dir: filepath.Join(t.TempDir(), "history")}
+	t0 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		run := HistoryRun{
+			Time:  t0.Add(time.Duration(i) * time.Minute),
+			Tests: []HistoryTest{{Package: "example.com/ex", Status: StatusPass}},
+		}
+		if err := h.Save(run, 3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(h.Dir, "20000101T000000.000000000Z.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runs, err := h.Runs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var times []time.Time
+	for _, run := range runs {
+		times = append(times, run.Time.UTC())
+	}
+	want := []time.Time{t0.Add(3 * time.Minute), t0.Add(2 * time.Minute), t0.Add(time.Minute)}
+	if !reflect.DeepEqual(times, want) {
+		t.Errorf("runs at %v, want %v", times, want)
+	}
+}
+
+func TestHistoryDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/cache")
+	t.Setenv("HOME", "/home/x")
+	dir, err := HistoryDir("example.com/some module")
+	if err != nil {
+		t.Skip(err)
+	}
+	if got := filepath.Base(dir); got != "example.com_some_module" {
+		t.Errorf("HistoryDir base = %q", got)
+	}
+}
+
+func TestSaveHistoryTags(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	ctx := context.Background()
+	flags := Flags{History: true, StillFailingRuns: 1, FlakyRuns: 20}
+	modules := []string{"example.com/history"}
+	key := Key{Package: "example.com/history", Test: "TestNew"}
+	newRun := func() TestStorage {
+		tests := NewTestStorage(&RunContext{
+			Modules:        modules,
+			FailureHistory: NewFailureHistory(ctx, flags, modules),
+		})
+		for _, e := range endedEvents(key, ActionFail, 0, "    a_test.go:1: bad") {
+			tests.Append(e)
+		}
+		tests.ResolveStatuses()
+		return tests
+	}
+
+	// no failure was tagged before the run was saved
+	first := newRun()
+	if err := first.SaveHistory(ctx, flags, nil); err != nil {
+		t.Fatal(err)
+	}
+	if first.FailureHistory.HasRuns() {
+		t.Error("the saved run is a previous run of itself")
+	}
+	if got := first.FailureHistory.Tag(key); got != "" {
+		t.Errorf("first run: got %q, want no tag", got)
+	}
+
+	second := newRun()
+	if got := second.FailureHistory.Tag(key); got != TagStillFailing {
+		t.Errorf("second run: got %q, want %q", got, TagStillFailing)
+	}
+}
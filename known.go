@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Tags of failures in FailureHistory.
+const (
+	TagNew          = "NEW"
+	TagStillFailing = "STILL FAILING"
+	TagKnownFlaky   = "KNOWN-FLAKY"
+)
+
+// FailureHistory holds the previous results on the current branch and its
+// merge base, used to tag each failure as new, still failing or known to be
+// flaky.
+type FailureHistory struct {
+	Runs         []map[Key]HistoryTest // newest first
+	StillFailing int                   // runs in a row a test must have failed in to be still failing
+	FlakyRuns    int                   // runs looked at for an intermittent history
+
+	load func() ([]map[Key]HistoryTest, error) // loads Runs, nil once they are
+}
+
+// NewFailureHistory returns the FailureHistory of the runs in the history on
+// the current branch or at its merge base with the default branch, all runs
+// outside git. The runs are loaded when the first failure is tagged, runs
+// without failures neither read the history nor run git.
+func NewFailureHistory(ctx context.Context, flags Flags, modules []string) *FailureHistory {
+	return &FailureHistory{
+		StillFailing: flags.StillFailingRuns,
+		FlakyRuns:    flags.FlakyRuns,
+		load: func() ([]map[Key]HistoryTest, error) {
+			return loadFailureRuns(ctx, modules)
+		},
+	}
+}
+
+// loadFailureRuns returns the results of the runs for a FailureHistory,
+// newest first.
+func loadFailureRuns(ctx context.Context, modules []string) ([]map[Key]HistoryTest, error) {
+	history, err := OpenHistory(modules)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := history.Runs()
+	if err != nil {
+		return nil, err
+	}
+	_, branch := gitHead(ctx)
+	base := gitMergeBase(ctx)
+	var results []map[Key]HistoryTest
+	for _, run := range runs {
+		sameBranch := branch != "" && branch != "HEAD" && run.Branch == branch
+		if branch == "" || sameBranch || (base != "" && run.Commit == base) {
+			results = append(results, run.Results())
+		}
+	}
+	return results, nil
+}
+
+// HasRuns reports whether there are previous runs to tag failures by, loading
+// them on first use. It is safe to call on a nil FailureHistory.
+func (h *FailureHistory) HasRuns() bool {
+	if h == nil {
+		return false
+	}
+	if h.load != nil {
+		runs, err := h.load()
+		h.load = nil
+		if err != nil {
+			fmt.Println("error loading history:", err)
+		}
+		h.Runs = runs
+	}
+	return len(h.Runs) > 0
+}
+
+// Tag returns the tag of the failure of key in the current run, "" if there is
+// no history. It is safe to call on a nil FailureHistory.
+func (h *FailureHistory) Tag(key Key) string {
+	if !h.HasRuns() {
+		return ""
+	}
+	// the results of key, newest first, the current failure included
+	failed := []bool{true}
+	flaky := false
+	for _, run := range h.Runs[:min(h.FlakyRuns, len(h.Runs))] {
+		t, ok := run[key]
+		if !ok || (!LastFailedStatuses.Any(t.Status) && t.Status != StatusPass && t.Status != StatusFlaky) {
+			continue
+		}
+		flaky = flaky || t.Status == StatusFlaky
+		failed = append(failed, LastFailedStatuses.Any(t.Status))
+	}
+	flips := 0
+	for i := 1; i < len(failed); i++ {
+		if failed[i] != failed[i-1] {
+			flips++
+		}
+	}
+	if flaky || flips >= 2 {
+		return TagKnownFlaky
+	}
+
+	streak := 0
+	for _, run := range h.Runs {
+		if t, ok := run[key]; !ok || !LastFailedStatuses.Any(t.Status) {
+			break
+		}
+		streak++
+	}
+	if streak > 0 && streak >= h.StillFailing {
+		return TagStillFailing
+	}
+	return TagNew
+}
+
+// tagColor returns the color of tag.
+func tagColor(tag string) func(a ...interface{}) string {
+	switch tag {
+	case TagNew:
+		return failColorBold
+	case TagKnownFlaky:
+		return flakyColorBold
+	default:
+		return noneColorBold
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestFailureHistoryTag(t *testing.T) {
+	key := Key{Package: "example.com/a", Test: "TestA"}
+	run := func(status Status) map[Key]HistoryTest {
+		return map[Key]HistoryTest{key: {Package: key.Package, Test: key.Test, Status: status}}
+	}
+	other := map[Key]HistoryTest{}
+	for _, tc := range []struct {
+		name string
+		runs []map[Key]HistoryTest
+		want string
+	}{
+		{"no runs", nil, ""},
+		{"passed before", []map[Key]HistoryTest{run(StatusPass), run(StatusPass)}, TagNew},
+		{"not in history", []map[Key]HistoryTest{other}, TagNew},
+		{"failed before", []map[Key]HistoryTest{run(StatusFail), run(StatusPanic)}, TagStillFailing},
+		{"failed once", []map[Key]HistoryTest{run(StatusFail), run(StatusPass)}, TagNew},
+		{"flaky before", []map[Key]HistoryTest{run(StatusPass), run(StatusFlaky)}, TagKnownFlaky},
+		{"intermittent", []map[Key]HistoryTest{run(StatusPass), run(StatusFail), run(StatusPass)}, TagKnownFlaky},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &FailureHistory{Runs: tc.runs, StillFailing: 2, FlakyRuns: 5}
+			if got := h.Tag(key); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+	var none *FailureHistory
+	if got := none.Tag(key); got != "" {
+		t.Errorf("nil FailureHistory: got %q", got)
+	}
+}
+
+func TestFailureHistoryLoad(t *testing.T) {
+	key := Key{Package: "example.com/a", Test: "TestA"}
+	loads := 0
+	h := &FailureHistory{
+		StillFailing: 1,
+		FlakyRuns:    5,
+		load: func() ([]map[Key]HistoryTest, error) {
+			loads++
+			return []map[Key]HistoryTest{{key: {Package: key.Package, Test: key.Test, Status: StatusFail}}}, nil
+		},
+	}
+	tests := NewTestStorage(&RunContext{FailureHistory: h})
+	if got := tests.failureTag(key, StatusPass); got != "" || loads != 0 {
+		t.Errorf("passed: got %q after %d loads, want no tag and no load", got, loads)
+	}
+	for i := 0; i < 2; i++ {
+		if got, want := tests.failureTag(key, StatusFail), "  "+TagStillFailing; got != want {
+			t.Errorf("failed: got %q, want %q", got, want)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loaded %d times, want once", loads)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// LastFailedStatuses are the statuses of the keys tgo last-failed runs again.
+var LastFailedStatuses = append(Statuses{StatusNone, StatusTimeout}, FailureStatuses...)
+
+// FailedKeys returns the keys of the tests in r that failed or never
+// finished. Keys whose subtests are included are left out since running a
+// subtest runs its parents too, package keys are only included for packages
+// without failed tests.
+func (r HistoryRun) FailedKeys() []Key {
+	failed := make(map[Key]bool)
+	for _, t := range r.Tests {
+		if LastFailedStatuses.Any(t.Status) {
+			failed[t.Key()] = true
+		}
+	}
+	var keys []Key
+	for key := range failed {
+		covered := false
+		for other := range failed {
+			if other == key || other.Package != key.Package {
+				continue
+			}
+			if key.Test == "" || other.IsSubtestOf(key) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Package != keys[j].Package {
+			return keys[i].Package < keys[j].Package
+		}
+		return keys[i].Test < keys[j].Test
+	})
+	return keys
+}
+
+// LastFailedArgs returns the go test arguments that run keys, one set per
+// -run pattern from RunPatterns with the packages that use it. The go test flags in argv other
+// than -run and -skip are added to each set, its packages are replaced.
+func LastFailedArgs(keys []Key, argv []string) [][]string {
+	tests := make(map[string][]string)
+	var packages []string
+	for _, key := range keys {
+		if _, ok := tests[key.Package]; !ok {
+			packages = append(packages, key.Package)
+		}
+		if key.Test == "" {
+			// the whole package failed, ie. to build
+			tests[key.Package] = nil
+			continue
+		}
+		tests[key.Package] = append(tests[key.Package], key.Test)
+	}
+
+	var (
+		order []string
+		byRun = make(map[string][]string)
+		flags []string
+	)
+	testFlags, _, binaryArgs := SplitArgs(argv)
+	for _, arg := range testFlags {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "run" && name != "skip" {
+			flags = append(flags, arg)
+		}
+	}
+	for _, pkg := range packages {
+		runs := RunPatterns(tests[pkg])
+		if len(runs) == 0 {
+			runs = []string{""}
+		}
+		for _, run := range runs {
+			if _, ok := byRun[run]; !ok {
+				order = append(order, run)
+			}
+			byRun[run] = append(byRun[run], pkg)
+		}
+	}
+	var argvs [][]string
+	for _, run := range order {
+		args := append([]string{}, flags...)
+		if run != "" {
+			args = append(args, "-run", run)
+		}
+		args = append(args, byRun[run]...)
+		argvs = append(argvs, append(args, binaryArgs...))
+	}
+	return argvs
+}
+
+// LastFailed returns the go test arguments that run the tests that failed in
+// the last run in the history, nil if none failed.
+func LastFailed(modules []string, argv []string) ([][]string, error) {
+	history, err := OpenHistory(modules)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := history.Runs()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, errors.New("there is no previous run in " + history.Dir)
+	}
+	keys := runs[0].FailedKeys()
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return LastFailedArgs(keys, argv), nil
+}
+
+// offerFullRun asks whether to run all tests when standard input is a
+// terminal.
+func offerFullRun() bool {
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Print("run the full set of tests? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// goTest runs go test -json with each of argvs in turn and returns their
+// combined output. wait returns the error of the first command that failed.
+func goTest(ctx context.Context, bin string, argvs [][]string) (stdout io.ReadCloser, wait func() error) {
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		var first error
+		for _, argv := range argvs {
+			cmd := exec.CommandContext(ctx, bin, append([]string{"test", "-json"}, argv...)...)
+			cmd.Stdout = w
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil && first == nil {
+				first = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		w.Close()
+		done <- first
+	}()
+	return r, func() error { return <-done }
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFailedKeys(t *testing.T) {
+	run := HistoryRun{Tests: []HistoryTest{
+		{Package: "example.com/a", Status: StatusFail},
+		{Package: "example.com/a", Test: "TestOwn", Status: StatusFail},
+		{Package: "example.com/a", Test: "TestOwn/bad", Status: StatusFail},
+		{Package: "example.com/a", Test: "TestPass", Status: StatusPass},
+		{Package: "example.com/a", Test: "TestHang", Status: StatusNone},
+		{Package: "example.com/build", Status: StatusFail},
+		{Package: "example.com/ok", Status: StatusPass},
+	}}
+	want := []Key{
+		{Package: "example.com/a", Test: "TestHang"},
+		{Package: "example.com/a", Test: "TestOwn/bad"},
+		{Package: "example.com/build"},
+	}
+	if got := run.FailedKeys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLastFailedArgs(t *testing.T) {
+	keys := []Key{
+		{Package: "example.com/a", Test: "TestA/x"},
+		{Package: "example.com/a", Test: "TestB"},
+		{Package: "example.com/b", Test: "TestA/x"},
+		{Package: "example.com/b", Test: "TestC"},
+		{Package: "example.com/build"},
+	}
+	for _, tc := range []struct {
+		name string
+		argv []string
+		want [][]string
+	}{
+		{
+			name: "no flags",
+			want: [][]string{
+				{"-run", "^TestA$/^x$", "example.com/a", "example.com/b"},
+				{"-run", "^TestB$", "example.com/a"},
+				{"-run", "^TestC$", "example.com/b"},
+				{"example.com/build"},
+			},
+		},
+		{
+			name: "packages and run replaced",
+			argv: []string{"-race", "-run", "TestOld", "-skip=TestSlow", "./...", "-args", "-update"},
+			want: [][]string{
+				{"-race", "-run", "^TestA$/^x$", "example.com/a", "example.com/b", "-args", "-update"},
+				{"-race", "-run", "^TestB$", "example.com/a", "-args", "-update"},
+				{"-race", "-run", "^TestC$", "example.com/b", "-args", "-update"},
+				{"-race", "example.com/build", "-args", "-update"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := LastFailedArgs(keys, tc.argv); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// UntilEnd returns the events of es up to and including its first ending
+// event, all of es if it never ended.
+func (es Events) UntilEnd() Events {
+	for i, e := range es {
+		if EndingActions.Any(e.Action) {
+			return es[:i+1]
+		}
+	}
+	return es
+}
+
+// LateOutput returns the output of es that arrived after its ending event,
+// e.g. from goroutines the test didn't wait for.
+func (es Events) LateOutput() Events {
+	var late Events
+	for _, e := range es[len(es.UntilEnd()):] {
+		if e.Action == ActionOutput && strings.TrimSpace(e.Output) != "" {
+			late = append(late, e)
+		}
+	}
+	return late
+}
+
+// GoroutineLeak is a panic caused by a goroutine that used a test after the
+// test completed. The panic is reported under whichever test was running at
+// the time, not the test that started the goroutine.
+type GoroutineLeak struct {
+	Key      Key    // the test that leaked the goroutine
+	Call     string // the method called after completion, e.g. "Log" or "Fail"
+	Message  string // the message passed to Log, if any
+	Reporter Key    // the test the panic was reported under
+}
+
+var goroutineLeakRe = regexp.MustCompile(`^(\w+) in goroutine after (.+) has completed(?:: (.*))?$`)
+
+// GoroutineLeak returns the leak that caused p, nil if p wasn't caused by a
+// goroutine outliving its test.
+func (p *Panic) GoroutineLeak() *GoroutineLeak {
+	m := goroutineLeakRe.FindStringSubmatch(p.Value)
+	if m == nil {
+		return nil
+	}
+	return &GoroutineLeak{
+		Key:      Key{Package: p.Key.Package, Test: m[2]},
+		Call:     m[1],
+		Message:  m[3],
+		Reporter: p.Key,
+	}
+}
+
+// FindGoroutineLeaks returns the goroutine leaks that caused panics in ts.
+func (ts TestStorage) FindGoroutineLeaks() []*GoroutineLeak {
+	var leaks []*GoroutineLeak
+	for _, key := range ts.OrderedKeys() {
+		if p := ts.Tests[key].FindPanic(); p != nil {
+			if leak := p.GoroutineLeak(); leak != nil {
+				leaks = append(leaks, leak)
+			}
+		}
+	}
+	return leaks
+}
+
+// PrintLateOutput prints the output that arrived after the tests in ts
+// completed and the tests that leaked goroutines which panicked after they
+// completed.
+func (ts TestStorage) PrintLateOutput(flags Flags) {
+	leaks := ts.FindGoroutineLeaks()
+	var keys []Key
+	for _, key := range ts.OrderedKeys() {
+		if key.Test != "" && len(ts.Tests[key].LateOutput()) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 && len(leaks) == 0 {
+		return
+	}
+
+	hr := timeoutColor("════════════")
+	fmt.Println(hr, timeoutColorBold("OUTPUT AFTER COMPLETION"), hr)
+	for _, leak := range leaks {
+		fmt.Println(timeoutColorBold("  LEAK") + " " +
+			ts.Links.LinkPackage(leak.Key.Package, packageColor(leak.Key.Package)) +
+			"." + ts.Links.LinkTest(leak.Key, testColorBold(leak.Key.Test)) +
+			"  " + timeoutColor(fmt.Sprintf("a goroutine called %s after the test completed, reported as a panic in %s",
+			leak.Call, leak.Reporter.TestName())))
+		if leak.Message != "" {
+			fmt.Println("         " + TruncateMiddle(leak.Message, flags.truncateWidth()))
+		}
+	}
+	for _, key := range keys {
+		status := ts.StatusOf(key)
+		fmt.Println(statusColorsBold[status]("===") + " " + statusColorsBold[status](statusNames[status]) + " " +
+			ts.Links.LinkPackage(key.Package, statusColors[status](key.Package)) +
+			"." + ts.Links.LinkTest(key, testColorBold(key.Test)) +
+			"  " + timeoutColor("output after the test completed"))
+		for _, line := range ts.Links.LinkRefs(ts.Tests[key].LateOutput().Render(flags, defaultColor)) {
+			fmt.Println(line.Text)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLateOutput(t *testing.T) {
+	key := Key{Package: "example.com/ex", Test: "TestX"}
+	es := outputEvents(key, "=== RUN   TestX", "    x_test.go:5: in time")
+	es = append(es, Event{Action: ActionPass, Package: key.Package, Test: key.Test})
+	es = append(es, outputEvents(key, "", "    x_test.go:9: too late")...)
+
+	if got := len(es.UntilEnd()); got != 3 {
+		t.Errorf("UntilEnd has %d events, want 3", got)
+	}
+	var late []string
+	for _, e := range es.LateOutput() {
+		late = append(late, e.Output)
+	}
+	if want := []string{"    x_test.go:9: too late\n"}; !reflect.DeepEqual(late, want) {
+		t.Errorf("LateOutput = %q, want %q", late, want)
+	}
+	if got := len(es[:2].UntilEnd()); got != 2 {
+		t.Errorf("UntilEnd of an unfinished test has %d events, want 2", got)
+	}
+}
+
+func TestGoroutineLeak(t *testing.T) {
+	reporter := Key{Package: "example.com/ex", Test: "TestB"}
+	for _, tc := range []struct {
+		value string
+		want  *GoroutineLeak
+	}{
+		{"boom", nil},
+		{
+			"Fail in goroutine after TestA has completed",
+			&GoroutineLeak{Key: Key{Package: "example.com/ex", Test: "TestA"}, Call: "Fail", Reporter: reporter},
+		},
+		{
+			"Log in goroutine after TestA/sub has completed: too late",
+			&GoroutineLeak{Key: Key{Package: "example.com/ex", Test: "TestA/sub"}, Call: "Log", Message: "too late", Reporter: reporter},
+		},
+	} {
+		p := &Panic{Key: reporter, Value: tc.value}
+		if got := p.GoroutineLeak(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %+v, want %+v", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestFindGoroutineLeaks(t *testing.T) {
+	tests := loadTests(t, "leak.json")
+	pkg := "example.com/ex/leak"
+	leaks := tests.FindGoroutineLeaks()
+	want := []*GoroutineLeak{{
+		Key:      Key{Package: pkg, Test: "TestLeaks"},
+		Call:     "Fail",
+		Reporter: Key{Package: pkg, Test: "TestRunning"},
+	}}
+	if !reflect.DeepEqual(leaks, want) {
+		t.Errorf("got %+v, want %+v", leaks, want)
+	}
+}
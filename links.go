@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// DefaultLinkTemplate links to local files with the line as anchor.
+const DefaultLinkTemplate = "file://{host}{path}#L{line}"
+
+// Linker creates terminal hyperlinks from a URL template with {host}, {path}
+// and {line} placeholders.
+type Linker struct {
+	Template string
+	Host     string
+
+	sources *SourceCache
+}
+
+// NewLinker returns a Linker for the links setting, nil if links are off.
+// "auto" enables links when colors are, which they are not when output isn't
+// a terminal. Packages and tests are only linked once sources has loaded
+// them, so nothing runs go list while go test output is read.
+func NewLinker(mode, template string, sources *SourceCache) (*Linker, error) {
+	switch mode {
+	case "never":
+		return nil, nil
+	case "auto":
+		if color.NoColor {
+			return nil, nil
+		}
+	case "always":
+	default:
+		return nil, fmt.Errorf("%s is not a valid links value, use auto, always or never", mode)
+	}
+	if template == "" {
+		template = DefaultLinkTemplate
+	}
+	host, _ := os.Hostname()
+	return &Linker{Template: template, Host: host, sources: sources}, nil
+}
+
+// URL returns the URL of line in the file at path. Absolute paths already
+// start with a slash, so a slash before {path} in the template is dropped
+// unless it ends the "//" of an empty host.
+func (l *Linker) URL(path string, line int) string {
+	if line < 1 {
+		line = 1
+	}
+	template := l.Template
+	if strings.HasPrefix(path, "/") && !strings.Contains(template, "://{path}") {
+		template = strings.ReplaceAll(template, "/{path}", "{path}")
+	}
+	return strings.NewReplacer(
+		"{host}", l.Host,
+		"{path}", (&url.URL{Path: path}).EscapedPath(),
+		"{line}", strconv.Itoa(line),
+	).Replace(template)
+}
+
+// hyperlink wraps text in an OSC 8 hyperlink to target.
+func hyperlink(target, text string) string {
+	return "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// LinkFile links text to line in the file at path. It is safe to call on a
+// nil Linker.
+func (l *Linker) LinkFile(path string, line int, text string) string {
+	if l == nil || path == "" {
+		return text
+	}
+	return hyperlink(l.URL(path, line), text)
+}
+
+// LinkPackage links text to the directory of pkg.
+func (l *Linker) LinkPackage(pkg, text string) string {
+	if l == nil || l.sources == nil {
+		return text
+	}
+	dir, ok := l.sources.Loaded(pkg)
+	if !ok {
+		return text
+	}
+	return hyperlink("file://"+l.Host+(&url.URL{Path: dir}).EscapedPath(), text)
+}
+
+// LinkTest links text to the function of the top level test of key.
+func (l *Linker) LinkTest(key Key, text string) string {
+	if l == nil || l.sources == nil {
+		return text
+	}
+	if _, ok := l.sources.Loaded(key.Package); !ok {
+		return text
+	}
+	ref, ok := l.sources.TestFunc(key.Package, key.Root().Test)
+	if !ok {
+		return text
+	}
+	return l.LinkFile(ref.File, ref.Line, text)
+}
+
+var outputFileRefRe = regexp.MustCompile(`[\w./@+~-]+\.go:\d+`)
+
+// LinkRefs links the file:line references in the rendered output lines. The
+// references are found in the output the lines were rendered from, as the
+// escape sequences of colored text can look like part of a file name.
+func (l *Linker) LinkRefs(lines []OutputLine) []OutputLine {
+	if l == nil || l.sources == nil {
+		return lines
+	}
+	for i, line := range lines {
+		// where to look for the next reference, links contain paths too
+		from := 0
+		for _, ref := range outputFileRefRe.FindAllString(line.Event.Output, -1) {
+			text := lines[i].Text
+			j := strings.Index(text[from:], ref)
+			if j < 0 {
+				continue
+			}
+			j += from
+			file, n, _ := strings.Cut(ref, ".go:")
+			lineNo, _ := strconv.Atoi(n)
+			path := file + ".go"
+			if !filepath.IsAbs(path) {
+				dir, ok := l.sources.Loaded(line.Event.Package)
+				if !ok {
+					continue
+				}
+				path = filepath.Join(dir, path)
+			}
+			link := l.LinkFile(path, lineNo, ref)
+			lines[i].Text = text[:j] + link + text[j+len(ref):]
+			from = j + len(link)
+		}
+	}
+	return lines
+}
+
+// TestFunc finds the declaration of the test, benchmark, fuzz or example
+// function name in pkg.
+func (c *SourceCache) TestFunc(pkg, name string) (FileRef, bool) {
+	funcs, ok := c.funcs[pkg]
+	if !ok {
+		funcs = make(map[string]FileRef)
+		c.funcs[pkg] = funcs
+		if c.Dir(pkg) != "" {
+			p := c.packages[pkg]
+			fset := token.NewFileSet()
+			files := append(append([]string{}, p.TestGoFiles...), p.XTestGoFiles...)
+			for _, name := range files {
+				filename := filepath.Join(p.Dir, name)
+				f, err := parser.ParseFile(fset, filename, nil, parser.SkipObjectResolution)
+				if err != nil {
+					continue
+				}
+				for _, decl := range f.Decls {
+					if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+						pos := fset.Position(fn.Pos())
+						funcs[fn.Name.Name] = FileRef{File: filename, Line: pos.Line}
+					}
+				}
+			}
+		}
+	}
+	ref, ok := funcs[name]
+	return ref, ok
+}
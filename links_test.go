@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewLinker(t *testing.T) {
+	for _, tc := range []struct {
+		mode    string
+		enabled bool
+		err     bool
+	}{
+		{"never", false, false},
+		{"always", true, false},
+		{"sometimes", false, true},
+	} {
+		l, err := NewLinker(tc.mode, "", nil)
+		if (err != nil) != tc.err || (l != nil) != tc.enabled {
+			t.Errorf("NewLinker(%q) = %v, %v", tc.mode, l, err)
+		}
+	}
+}
+
+func TestLinkerURL(t *testing.T) {
+	for _, tc := range []struct {
+		template string
+		path     string
+		line     int
+		want     string
+	}{
+		{DefaultLinkTemplate, "/src/x_test.go", 12, "file://host/src/x_test.go#L12"},
+		{DefaultLinkTemplate, "/src/a b.go", 0, "file://host/src/a%20b.go#L1"},
+		{"vscode://file/{path}:{line}", "/src/x_test.go", 12, "vscode://file/src/x_test.go:12"},
+		{"vscode://file{path}:{line}", "/src/x_test.go", 12, "vscode://file/src/x_test.go:12"},
+		{"file://{path}", "/src/x_test.go", 12, "file:///src/x_test.go"},
+	} {
+		l := &Linker{Template: tc.template, Host: "host"}
+		if got := l.URL(tc.path, tc.line); got != tc.want {
+			t.Errorf("URL(%q, %q, %d) = %q, want %q", tc.template, tc.path, tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestLinkRefs(t *testing.T) {
+	l := &Linker{Template: "file://{path}#L{line}", sources: NewSourceCache(context.Background(), "go")}
+	key := Key{Package: "example.com/x", Test: "TestX"}
+	link := func(path, line, text string) string {
+		return hyperlink("file://"+path+"#L"+line, text)
+	}
+	for _, tc := range []struct {
+		name   string
+		output string
+		text   string
+		want   string
+	}{
+		{
+			name:   "colored",
+			output: "    /src/x_test.go:12: got /src/y.go:3\n",
+			text:   "\x1b[31m    /src/x_test.go:12: got /src/y.go:3\x1b[0m",
+			want:   "\x1b[31m    " + link("/src/x_test.go", "12", "/src/x_test.go:12") + ": got " + link("/src/y.go", "3", "/src/y.go:3") + "\x1b[0m",
+		},
+		{
+			name:   "rendered away",
+			output: "    /src/x_test.go:12: got 1\n",
+			text:   "        > 12 | t.Error(1)",
+			want:   "        > 12 | t.Error(1)",
+		},
+		{
+			name:   "same ref twice",
+			output: "/src/x_test.go:1 /src/x_test.go:1\n",
+			text:   "/src/x_test.go:1 /src/x_test.go:1",
+			want:   link("/src/x_test.go", "1", "/src/x_test.go:1") + " " + link("/src/x_test.go", "1", "/src/x_test.go:1"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := Event{Action: ActionOutput, Package: key.Package, Test: key.Test, Output: tc.output}
+			lines := l.LinkRefs([]OutputLine{{Event: e, Text: tc.text}})
+			if lines[0].Text != tc.want {
+				t.Errorf("got %q, want %q", lines[0].Text, tc.want)
+			}
+		})
+	}
+}
+
+func TestLinkUnloaded(t *testing.T) {
+	sources := NewSourceCache(context.Background(), "go")
+	l := &Linker{Template: "file://{path}#L{line}", sources: sources}
+	key := Key{Package: "example.com/x", Test: "TestX"}
+	e := Event{Action: ActionOutput, Package: key.Package, Test: key.Test, Output: "    x_test.go:12: bad\n"}
+	text := "    x_test.go:12: bad"
+
+	// nothing is looked up while go test output is read
+	if got := l.LinkPackage(key.Package, "x"); got != "x" {
+		t.Errorf("LinkPackage = %q, want plain text", got)
+	}
+	if got := l.LinkRefs([]OutputLine{{Event: e, Text: text}})[0].Text; got != text {
+		t.Errorf("LinkRefs = %q, want plain text", got)
+	}
+	if _, ok := sources.packages[key.Package]; ok {
+		t.Error("linking looked up the package")
+	}
+
+	sources.packages[key.Package] = Package{Dir: "/src/x"}
+	want := "    " + hyperlink("file:///src/x/x_test.go#L12", "x_test.go:12") + ": bad"
+	if got := l.LinkRefs([]OutputLine{{Event: e, Text: text}})[0].Text; got != want {
+		t.Errorf("LinkRefs = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LogLevel is the severity of a structured log line, using the log/slog
+// values.
+type LogLevel int
+
+const (
+	LogTrace LogLevel = -8
+	LogDebug LogLevel = -4
+	LogInfo  LogLevel = 0
+	LogWarn  LogLevel = 4
+	LogError LogLevel = 8
+	LogFatal LogLevel = 12
+)
+
+var logLevelNames = map[string]LogLevel{
+	"trace":    LogTrace,
+	"debug":    LogDebug,
+	"info":     LogInfo,
+	"warn":     LogWarn,
+	"warning":  LogWarn,
+	"error":    LogError,
+	"err":      LogError,
+	"dpanic":   LogFatal,
+	"panic":    LogFatal,
+	"fatal":    LogFatal,
+	"critical": LogFatal,
+}
+
+// ParseLogLevel parses a level name like "warn" or the log/slog form
+// "INFO+2".
+func ParseLogLevel(s string) (LogLevel, error) {
+	name, offset := strings.ToLower(s), 0
+	if i := strings.IndexAny(name, "+-"); i > 0 {
+		n, err := strconv.Atoi(name[i:])
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid log level", s)
+		}
+		name, offset = name[:i], n
+	}
+	level, ok := logLevelNames[name]
+	if !ok {
+		return 0, fmt.Errorf("%s is not a valid log level, use debug, info, warn or error", s)
+	}
+	return level + LogLevel(offset), nil
+}
+
+// LogField is a key and value of a structured log line.
+type LogField struct {
+	Key   string
+	Value string
+}
+
+// LogLine is a structured log line.
+type LogLine struct {
+	Time   string
+	Level  string
+	Msg    string
+	Fields []LogField
+}
+
+// ParseLogLine parses s as a JSON or logfmt log line like the ones written by
+// log/slog and zap. Lines without a level or message are not log lines.
+func ParseLogLine(s string) (LogLine, bool) {
+	s = strings.TrimSpace(s)
+	var (
+		fields []LogField
+		ok     bool
+	)
+	if strings.HasPrefix(s, "{") {
+		fields, ok = parseJSONFields(s)
+	} else {
+		fields, ok = parseLogfmtFields(s)
+	}
+	if !ok {
+		return LogLine{}, false
+	}
+	var l LogLine
+	for _, f := range fields {
+		switch strings.ToLower(f.Key) {
+		case "time", "ts", "timestamp", "@timestamp":
+			if l.Time == "" {
+				l.Time = f.Value
+				continue
+			}
+		case "level", "lvl", "severity":
+			if l.Level == "" {
+				l.Level = f.Value
+				continue
+			}
+		case "msg", "message":
+			if l.Msg == "" {
+				l.Msg = f.Value
+				continue
+			}
+		}
+		l.Fields = append(l.Fields, f)
+	}
+	if l.Level == "" && l.Msg == "" {
+		return LogLine{}, false
+	}
+	return l, true
+}
+
+// parseJSONFields parses the fields of a JSON object in order. Nested values
+// are kept as compact JSON.
+func parseJSONFields(s string) ([]LogField, bool) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, false
+	}
+	var fields []LogField
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, _ := t.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, false
+		}
+		value := string(raw)
+		var str string
+		if json.Unmarshal(raw, &str) == nil {
+			value = str
+		} else {
+			var buf bytes.Buffer
+			if json.Compact(&buf, raw) == nil {
+				value = buf.String()
+			}
+		}
+		fields = append(fields, LogField{Key: key, Value: value})
+	}
+	if t, err := dec.Token(); err != nil || t != json.Delim('}') {
+		return nil, false
+	}
+	return fields, dec.InputOffset() == int64(len(s))
+}
+
+// parseLogfmtFields parses s as space separated key=value pairs, values
+// containing spaces are quoted.
+func parseLogfmtFields(s string) ([]LogField, bool) {
+	var fields []LogField
+	for s != "" {
+		i := strings.IndexAny(s, "= \"")
+		if i <= 0 || s[i] != '=' {
+			return nil, false
+		}
+		key := s[:i]
+		s = s[i+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, false
+			}
+			value, _ = strconv.Unquote(quoted)
+			s = s[len(quoted):]
+			if s != "" && s[0] != ' ' {
+				return nil, false
+			}
+		} else {
+			value, s, _ = strings.Cut(s, " ")
+		}
+		fields = append(fields, LogField{Key: key, Value: value})
+		s = strings.TrimLeft(s, " ")
+	}
+	return fields, len(fields) >= 2
+}
+
+// logLevelColor returns the color of level.
+func logLevelColor(level string) func(a ...interface{}) string {
+	l, err := ParseLogLevel(level)
+	switch {
+	case err != nil:
+		return defaultColor
+	case l >= LogError:
+		return failColorBold
+	case l >= LogWarn:
+		return timeoutColorBold
+	case l >= LogInfo:
+		return passColor
+	default:
+		return timeColor
+	}
+}
+
+// noisyLogFields are hidden below verbosity V2.
+var noisyLogFields = map[string]bool{
+	"caller": true,
+	"source": true,
+}
+
+// logPrefix returns the indentation and file:line prefix t.Log adds to line.
+func logPrefix(line string) string {
+	if m := fileRefRe.FindString(line); m != "" {
+		return m
+	}
+	return leadingSpace(line)
+}
+
+// renderLog renders consecutive JSON and logfmt log lines as aligned, level
+// colored lines. Lines below TGO_LOG_LEVEL are dropped and timestamps are
+// only shown from verbosity V2.
+func renderLog(flags Flags, es Events, textColor func(a ...interface{}) string) ([]OutputLine, int) {
+	minLevel, minErr := ParseLogLevel(flags.LogLevel)
+
+	type parsed struct {
+		event  Event
+		prefix string
+		line   LogLine
+	}
+	var (
+		block []parsed
+		n     int
+	)
+	for _, e := range es {
+		if e.Action != ActionOutput {
+			break
+		}
+		output := strings.TrimSuffix(e.Output, "\n")
+		prefix := logPrefix(output)
+		l, ok := ParseLogLine(output[len(prefix):])
+		if !ok {
+			break
+		}
+		n++
+		if level, err := ParseLogLevel(l.Level); minErr == nil && err == nil && level < minLevel {
+			continue
+		}
+		block = append(block, parsed{event: e, prefix: prefix, line: l})
+	}
+	if n == 0 {
+		return nil, 0
+	}
+
+	var levelWidth, msgWidth int
+	for _, p := range block {
+		levelWidth = max(levelWidth, len(p.line.Level))
+		msgWidth = max(msgWidth, len([]rune(p.line.Msg)))
+	}
+	msgWidth = min(msgWidth, 60)
+
+	var lines []OutputLine
+	for _, p := range block {
+		var sb strings.Builder
+		sb.WriteString(p.prefix)
+		if flags.V >= V2 && p.line.Time != "" {
+			sb.WriteString(timeColor(p.line.Time) + " ")
+		}
+		if levelWidth > 0 {
+			level := strings.ToUpper(p.line.Level)
+			sb.WriteString(logLevelColor(level)(fmt.Sprintf("%-*s", levelWidth, level)) + " ")
+		}
+		msg := TruncateMiddle(p.line.Msg, flags.truncateWidth())
+		sb.WriteString(textColor(msg))
+		var fields []string
+		for _, f := range p.line.Fields {
+			if flags.V < V2 && noisyLogFields[f.Key] {
+				continue
+			}
+			value := TruncateMiddle(f.Value, flags.truncateWidth())
+			isJSON := strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[")
+			if value == "" || !isJSON && strings.ContainsAny(value, " \t\"=") {
+				value = strconv.Quote(value)
+			}
+			fields = append(fields, timeColor(f.Key+"=")+value)
+		}
+		if len(fields) > 0 {
+			if pad := msgWidth - len([]rune(msg)); pad > 0 {
+				sb.WriteString(strings.Repeat(" ", pad))
+			}
+			sb.WriteString("  " + strings.Join(fields, " "))
+		}
+		lines = append(lines, OutputLine{Event: p.event, Text: sb.String()})
+	}
+	return lines, n
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want LogLevel
+		err  bool
+	}{
+		{"info", LogInfo, false},
+		{"WARN", LogWarn, false},
+		{"warning", LogWarn, false},
+		{"INFO+2", LogInfo + 2, false},
+		{"error-1", LogError - 1, false},
+		{"loud", 0, true},
+		{"info+x", 0, true},
+	} {
+		got, err := ParseLogLevel(tc.s)
+		if got != tc.want || (err != nil) != tc.err {
+			t.Errorf("ParseLogLevel(%q) = %v, %v, want %v, error %v", tc.s, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		s    string
+		want LogLine
+		ok   bool
+	}{
+		{
+			name: "slog json",
+			s:    `{"time":"2024-01-02T03:04:05Z","level":"INFO","msg":"started","port":8080,"tags":["a", "b"]}`,
+			want: LogLine{
+				Time:   "2024-01-02T03:04:05Z",
+				Level:  "INFO",
+				Msg:    "started",
+				Fields: []LogField{{"port", "8080"}, {"tags", `["a","b"]`}},
+			},
+			ok: true,
+		},
+		{
+			name: "logfmt",
+			s:    `time=2024-01-02T03:04:05Z level=WARN msg="slow query" took=2s`,
+			want: LogLine{
+				Time:   "2024-01-02T03:04:05Z",
+				Level:  "WARN",
+				Msg:    "slow query",
+				Fields: []LogField{{"took", "2s"}},
+			},
+			ok: true,
+		},
+		{name: "json without level or message", s: `{"a":1}`},
+		{name: "json with trailing text", s: `{"msg":"x"} and more`},
+		{name: "text", s: "got 1, want 2"},
+		{name: "single pair", s: "level=info"},
+		{name: "bad quoting", s: `level=info msg="open`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ParseLogLine(tc.s)
+			if ok != tc.ok || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestRenderLog(t *testing.T) {
+	key := Key{Package: "example.com/ex", Test: "TestX"}
+	es := outputEvents(key,
+		`    x_test.go:10: level=debug msg=connecting`,
+		`    x_test.go:11: level=info msg=connected caller=db.go:12 addr=":5432"`,
+		`    x_test.go:12: level=error msg="query failed" err="no rows"`,
+		`    x_test.go:13: got 1, want 2`,
+	)
+	for _, tc := range []struct {
+		name  string
+		flags Flags
+		want  []string
+	}{
+		{
+			name: "all",
+			want: []string{
+				`    x_test.go:10: DEBUG connecting`,
+				`    x_test.go:11: INFO  connected     addr=:5432`,
+				`    x_test.go:12: ERROR query failed  err="no rows"`,
+			},
+		},
+		{
+			name:  "warn and above",
+			flags: Flags{LogLevel: "warn"},
+			want: []string{
+				`    x_test.go:12: ERROR query failed  err="no rows"`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lines, n := renderLog(tc.flags, es, fmt.Sprint)
+			if n != 3 {
+				t.Errorf("consumed %d events, want 3", n)
+			}
+			if got := renderedText(lines); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CodeOwnersRule is a line of a CODEOWNERS file.
+type CodeOwnersRule struct {
+	Pattern string
+	Owners  []string
+	re      *regexp.Regexp
+}
+
+// CodeOwners is a parsed CODEOWNERS file.
+type CodeOwners struct {
+	Root  string // the directory patterns are relative to
+	Rules []CodeOwnersRule
+
+	sources *SourceCache
+}
+
+// FindCodeOwners finds the CODEOWNERS file of the repository containing dir
+// in the locations GitHub and GitLab look for it. It returns "" if there is
+// none.
+func FindCodeOwners(dir string) string {
+	return findUp(dir, ".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS")
+}
+
+// findUp returns the first of names found in dir or its parents up to the root
+// of the repository, "" if none is found.
+func findUp(dir string, names ...string) string {
+	for {
+		for _, name := range names {
+			filename := filepath.Join(dir, name)
+			if _, err := os.Stat(filename); err == nil {
+				return filename
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ReadCodeOwners reads the CODEOWNERS file filename. The directories of
+// packages are looked up in sources.
+func ReadCodeOwners(filename string, sources *SourceCache) (*CodeOwners, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	root := filepath.Dir(filename)
+	if base := filepath.Base(root); base == ".github" || base == "docs" || base == ".gitlab" {
+		root = filepath.Dir(root)
+	}
+	co := &CodeOwners{Root: root, sources: sources}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var owners []string
+		for _, o := range fields[1:] {
+			if strings.HasPrefix(o, "#") {
+				break
+			}
+			owners = append(owners, o)
+		}
+		re, err := codeOwnersPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %s: %w", filename, fields[0], err)
+		}
+		co.Rules = append(co.Rules, CodeOwnersRule{Pattern: fields[0], Owners: owners, re: re})
+	}
+	return co, scanner.Err()
+}
+
+// codeOwnersPattern translates a gitignore style CODEOWNERS pattern to a
+// regular expression matching slash separated paths relative to the root.
+// A pattern matching a directory matches everything below it.
+func codeOwnersPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	p := strings.Trim(pattern, "/")
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case p[i] == '*':
+			sb.WriteString("[^/]*")
+		case p[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	sb.WriteString("(?:/.*)?$")
+	return regexp.Compile(sb.String())
+}
+
+// Owners returns the owners of path, relative to the root, from the last
+// matching rule.
+func (co *CodeOwners) Owners(path string) []string {
+	path = filepath.ToSlash(path)
+	for i := len(co.Rules) - 1; i >= 0; i-- {
+		if co.Rules[i].re.MatchString(path) {
+			return co.Rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// Load looks up the directories of the packages of keys with a single go list,
+// so KeyOwners doesn't run one per package. It is safe to call on a nil
+// CodeOwners.
+func (co *CodeOwners) Load(keys []Key) {
+	if co == nil {
+		return
+	}
+	var pkgs []string
+	for _, key := range keys {
+		pkgs = append(pkgs, key.Package)
+	}
+	co.sources.Load(pkgs)
+}
+
+// KeyOwners returns the owners of the directory of the package of key. It is
+// safe to call on a nil CodeOwners.
+func (co *CodeOwners) KeyOwners(key Key) []string {
+	if co == nil {
+		return nil
+	}
+	dir := co.sources.Dir(key.Package)
+	if dir == "" {
+		return nil
+	}
+	rel, err := filepath.Rel(co.Root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	return co.Owners(rel)
+}
+
+// PrintFailures prints the failed keys grouped by the owners of their
+// packages. It is safe to call on a nil CodeOwners.
+func (co *CodeOwners) PrintFailures(links *Linker, keys []Key) {
+	if co == nil || len(keys) == 0 {
+		return
+	}
+	co.Load(keys)
+	var owners []string
+	byOwner := make(map[string][]Key)
+	for _, key := range keys {
+		o := strings.Join(co.KeyOwners(key), " ")
+		if _, ok := byOwner[o]; !ok {
+			owners = append(owners, o)
+		}
+		byOwner[o] = append(byOwner[o], key)
+	}
+
+	hr := failColor("════════════")
+	fmt.Println(hr, failColorBold("FAILURES BY OWNER"), hr)
+	for _, o := range owners {
+		keys := byOwner[o]
+		name := o
+		if name == "" {
+			name = "(no owner)"
+		}
+		fmt.Println("  " + failColorBold(name) + "  " + timeColor(fmt.Sprintf("%d failed", len(keys))))
+		for _, key := range keys {
+			text := links.LinkPackage(key.Package, packageColor(key.Package))
+			if key.Test != "" {
+				text += "." + links.LinkTest(key, testColor(key.Test))
+			}
+			fmt.Println("    " + text)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCodeOwnersPattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*", "a/b/c.go", true},
+		{"*.go", "a/b/c.go", true},
+		{"*.go", "a/b/c.txt", false},
+		{"docs", "docs/a.md", true},
+		{"docs", "x/docs/a.md", true},
+		{"/docs", "x/docs/a.md", false},
+		{"pkg/api/", "pkg/api/v1/x.go", true},
+		{"pkg/api/", "other/pkg/api/x.go", false},
+		{"pkg/*.go", "pkg/x.go", true},
+		{"pkg/*.go", "pkg/sub/x.go", false},
+		{"**/testdata", "a/b/testdata/x.json", true},
+		{"pkg/**/gen", "pkg/a/b/gen/x.go", true},
+		{"file?.go", "file1.go", true},
+	} {
+		re, err := codeOwnersPattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.pattern, err)
+		}
+		if got := re.MatchString(tc.path); got != tc.want {
+			t.Errorf("%s matches %s = %v, want %v", tc.pattern, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestReadCodeOwners(t *testing.T) {
+	root := t.TempDir()
+	filename := filepath.Join(root, ".github", "CODEOWNERS")
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data := `# owners
+*            @org/all
+/pkg/api/    @org/api @ada # the api
+
+[Section]
+pkg/api/internal/
+`
+	if err := os.WriteFile(filename, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindCodeOwners(filepath.Join(root, "pkg")); got != filename {
+		t.Errorf("FindCodeOwners = %q, want %q", got, filename)
+	}
+
+	sources := NewSourceCache(context.Background(), "go")
+	sources.packages["example.com/api"] = Package{Dir: filepath.Join(root, "pkg", "api")}
+	sources.packages["example.com/internal"] = Package{Dir: filepath.Join(root, "pkg", "api", "internal")}
+	sources.packages["example.com/cmd"] = Package{Dir: filepath.Join(root, "cmd")}
+	sources.packages["example.com/outside"] = Package{Dir: t.TempDir()}
+	sources.packages["example.com/unknown"] = Package{}
+	co, err := ReadCodeOwners(filename, sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if co.Root != root {
+		t.Errorf("Root = %q, want %q", co.Root, root)
+	}
+	for pkg, want := range map[string][]string{
+		"example.com/api":      {"@org/api", "@ada"},
+		"example.com/internal": nil,
+		"example.com/cmd":      {"@org/all"},
+		"example.com/outside":  nil,
+		"example.com/unknown":  nil,
+	} {
+		if got := co.KeyOwners(Key{Package: pkg, Test: "TestX"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: owners %q, want %q", pkg, got, want)
+		}
+	}
+}
+
+func TestCodeOwnersLoad(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script as go binary")
+	}
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	bin := filepath.Join(dir, "go")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho \"$@\" >> "+calls+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	co := &CodeOwners{Root: dir, sources: NewSourceCache(context.Background(), bin)}
+	keys := []Key{
+		{Package: "example.com/a", Test: "TestA"},
+		{Package: "example.com/b", Test: "TestB"},
+		{Package: "example.com/a", Test: "TestC"},
+	}
+	co.Load(keys)
+	for _, key := range keys {
+		co.KeyOwners(key)
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Errorf("go ran %d times, want once:\n%s", n, data)
+	}
+}
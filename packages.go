@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+)
+
+// Package is the subset of `go list -json` output tgo uses.
+type Package struct {
+	ImportPath   string
+	Dir          string
+	GoFiles      []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+}
+
+// Packages maps import paths to packages.
+type Packages map[string]Package
+
+// ListPackages runs go list -json for the import paths in pkgs. Packages that
+// can't be listed are left out.
+func ListPackages(ctx context.Context, bin string, pkgs []string) (Packages, error) {
+	packages := make(Packages, len(pkgs))
+	if len(pkgs) == 0 {
+		return packages, nil
+	}
+	args := append([]string{"list", "-e", "-json"}, pkgs...)
+	out, err := exec.CommandContext(ctx, bin, args...).Output()
+	if err != nil {
+		return packages, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var p Package
+		if err := dec.Decode(&p); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return packages, err
+		}
+		if p.Dir != "" {
+			packages[p.ImportPath] = p
+		}
+	}
+	return packages, nil
+}
+
+// Packages returns the import paths of the packages in ts.
+func (ts TestStorage) Packages() []string {
+	var pkgs []string
+	seen := make(map[string]bool)
+	for _, key := range ts.OrderedKeys() {
+		if !seen[key.Package] {
+			seen[key.Package] = true
+			pkgs = append(pkgs, key.Package)
+		}
+	}
+	return pkgs
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maruel/natural"
+)
+
+// findModules lists the module paths of the main module or workspace in the
+// current directory.
+func findModules(ctx context.Context, bin string) []string {
+	out, err := exec.CommandContext(ctx, bin, "list", "-m").Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(out))
+}
+
+// Frame is a function call in a goroutine stack trace.
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+// InModule reports whether the frame is a function in one of modules. If the
+// modules are unknown all non standard library functions are considered to be
+// in the module.
+func (f Frame) InModule(modules []string) bool {
+	if len(modules) == 0 {
+		first, _, ok := strings.Cut(f.Func, "/")
+		return ok && strings.Contains(first, ".") && !strings.HasPrefix(f.Func, "created by ")
+	}
+	fn := strings.TrimPrefix(f.Func, "created by ")
+	for _, m := range modules {
+		if strings.HasPrefix(fn, m+".") || strings.HasPrefix(fn, m+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("%s  %s:%d", f.Func, f.File, f.Line)
+}
+
+// Goroutine is a single goroutine from a stack dump.
+type Goroutine struct {
+	Header string // ie. "goroutine 6 [running]:"
+	Frames []Frame
+}
+
+// ModuleFrames returns the frames of g that are inside modules.
+func (g Goroutine) ModuleFrames(modules []string) []Frame {
+	var frames []Frame
+	for _, f := range g.Frames {
+		if f.InModule(modules) {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+// Panic is a panic found in the output of a test.
+type Panic struct {
+	Key        Key
+	Value      string
+	Goroutines []Goroutine
+}
+
+var (
+	goroutineRe = regexp.MustCompile(`^goroutine \d+ \[.*\]:$`)
+	frameFileRe = regexp.MustCompile(`^\t(.*\.(?:go|s)):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+// parseStack parses the frames of a goroutine stack starting at lines[i],
+// the line after the goroutine header. It returns the frames and the index of
+// the first line after them.
+func parseStack(lines []string, i int) ([]Frame, int) {
+	var frames []Frame
+	for i+1 < len(lines) {
+		m := frameFileRe.FindStringSubmatch(lines[i+1])
+		fn := strings.TrimSuffix(lines[i], "\n")
+		if m == nil || fn == "" || strings.HasPrefix(fn, "\t") {
+			break
+		}
+		line, _ := strconv.Atoi(m[2])
+		fn, _, _ = strings.Cut(fn, " in goroutine ")
+		if j := strings.LastIndex(fn, "("); j > 0 && strings.HasSuffix(fn, ")") {
+			fn = fn[:j]
+		}
+		frames = append(frames, Frame{Func: fn, File: m[1], Line: line})
+		i += 2
+	}
+	return frames, i
+}
+
+// outputLines returns the output of every output event in es, one line each.
+func (es Events) outputLines() []string {
+	var lines []string
+	for _, e := range es {
+		if e.Action == ActionOutput {
+			lines = append(lines, strings.TrimSuffix(e.Output, "\n"))
+		}
+	}
+	return lines
+}
+
+// FindPanic finds a panic in the output of es, nil if there isn't one.
+func (es Events) FindPanic() *Panic {
+	lines := es.outputLines()
+	var p *Panic
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if p == nil {
+			if strings.HasPrefix(line, "panic: ") {
+				value := strings.TrimPrefix(line, "panic: ")
+				if j := strings.LastIndex(value, " [recovered"); j >= 0 {
+					value = value[:j]
+				}
+				p = &Panic{Key: es[0].Key(), Value: value}
+			}
+			continue
+		}
+		if goroutineRe.MatchString(line) {
+			var frames []Frame
+			frames, i = parseStack(lines, i+1)
+			p.Goroutines = append(p.Goroutines, Goroutine{Header: line, Frames: frames})
+			i--
+		}
+	}
+	return p
+}
+
+// FindAbortingPanic returns the panic in another test of the same package
+// that crashed the test binary before key got to finish.
+func (ts TestStorage) FindAbortingPanic(key Key) *Panic {
+	if ts.Tests[key].FindFirstByAction(EndingActions...) != nil {
+		return nil
+	}
+	tests := ts.FindPackageTests(key.Package)
+	for _, k := range tests.OrderedKeys() {
+		if k == key {
+			continue
+		}
+		if p := tests.Tests[k].FindPanic(); p != nil && !p.IsTimeout() {
+			return p
+		}
+	}
+	return nil
+}
+
+// failedSilently reports whether es failed without a message of its own. go
+// test marks t.Error output since go1.24, before that any output counts.
+func (es Events) failedSilently() bool {
+	typed := false
+	for _, e := range es {
+		if strings.HasPrefix(e.OutputType, "error") {
+			return false
+		}
+		typed = typed || e.OutputType != ""
+	}
+	return typed || !es.HasOwnOutput()
+}
+
+// panickedSubtest returns the subtest of the top level test key that a panic
+// reported in the output of key came from: the last of its subtests to fail
+// silently without failing subtests of its own. go test reports the failure
+// of a panicking subtest and of its parents before printing the panic, it is
+// false if key didn't report its failure yet.
+func (ts TestStorage) panickedSubtest(key Key) (Key, bool) {
+	reported := false
+	for _, line := range ts.Tests[key].outputLines() {
+		if strings.HasPrefix(strings.TrimSpace(line), "--- FAIL: "+key.Test+" (") {
+			reported = true
+		}
+	}
+	if !reported {
+		return Key{}, false
+	}
+	failed := ts.FindSubtests(key).FindByAction(ActionFail)
+	var (
+		panicked Key
+		last     time.Time
+	)
+	for k, events := range failed.Tests {
+		leaf := true
+		for other := range failed.Tests {
+			if other.IsSubtestOf(k) {
+				leaf = false
+				break
+			}
+		}
+		end := events.FindFirstByAction(ActionFail)
+		if leaf && events.failedSilently() && !end.Time.Before(last) {
+			panicked, last = k, end.Time
+		}
+	}
+	return panicked, panicked.Test != ""
+}
+
+// keySet is a set of keys.
+type keySet map[Key]bool
+
+// Pop removes the subtests of key from s and returns them in order, all keys
+// for the zero key.
+func (s keySet) Pop(key Key) []Key {
+	var keys []Key
+	for k := range s {
+		if key == (Key{}) || k.IsSubtestOf(key) {
+			keys = append(keys, k)
+			delete(s, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return natural.Less(keys[i].String(), keys[j].String())
+	})
+	return keys
+}
+
+// appendPanic appends e to the subtest that panicked when e is the output of
+// a subtest panic reported under its top level test, ahead of the ending event
+// of the subtest. It reports whether e was appended.
+func (ts TestStorage) appendPanic(e Event) bool {
+	key := e.Key()
+	sub, ok := ts.panicking[key]
+	switch {
+	case ok && e.Action != ActionOutput:
+		// the subtest ended before its panic was reported
+		delete(ts.panicking, key)
+		ts.statuses[sub] = ts.status(sub)
+		return false
+	case ok:
+	case e.Action == ActionOutput && key.Test != "" && key.Depth() == 0 &&
+		strings.HasPrefix(e.Output, "panic: ") &&
+		!strings.HasPrefix(e.Output, "panic: test timed out after "):
+		if sub, ok = ts.panickedSubtest(key); !ok {
+			return false
+		}
+		ts.panicking[key] = sub
+		delete(ts.statuses, sub)
+	default:
+		return false
+	}
+	e.Test = sub.Test
+	events := ts.Tests[sub]
+	i := len(events)
+	for j, se := range events {
+		if EndingActions.Any(se.Action) {
+			i = j
+			break
+		}
+	}
+	ts.Tests[sub] = append(events[:i:i], append(Events{e}, events[i:]...)...)
+	return true
+}
+
+// CollapseStacks replaces goroutine stack dumps in the output of es with their
+// frames in modules, other frames are folded into a count.
+func (es Events) CollapseStacks(modules []string) Events {
+	var events Events
+	for i := 0; i < len(es); i++ {
+		e := es[i]
+		events = append(events, e)
+		if e.Action != ActionOutput || !goroutineRe.MatchString(strings.TrimSuffix(e.Output, "\n")) {
+			continue
+		}
+		var lines []string
+		for _, e := range es[i+1:] {
+			if e.Action != ActionOutput {
+				break
+			}
+			lines = append(lines, strings.TrimSuffix(e.Output, "\n"))
+		}
+		frames, n := parseStack(lines, 0)
+		var hidden int
+		for _, f := range frames {
+			if !f.InModule(modules) {
+				hidden++
+				continue
+			}
+			fe := e
+			fe.Output = "    " + f.String() + "\n"
+			events = append(events, fe)
+		}
+		if hidden > 0 {
+			fe := e
+			fe.Output = fmt.Sprintf("    … %d frames outside the module hidden\n", hidden)
+			events = append(events, fe)
+		}
+		i += n
+	}
+	return events
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// outputEvents returns output events of key with lines as output.
+func outputEvents(key Key, lines ...string) Events {
+	var es Events
+	for _, line := range lines {
+		es = append(es, Event{Action: ActionOutput, Package: key.Package, Test: key.Test, Output: line + "\n"})
+	}
+	return es
+}
+
+func TestFindPanic(t *testing.T) {
+	key := Key{Package: "example.com/ex", Test: "TestX"}
+	for _, tc := range []struct {
+		name   string
+		lines  []string
+		value  string
+		frames []Frame
+	}{
+		{
+			name:  "none",
+			lines: []string{"=== RUN   TestX", "--- PASS: TestX (0.00s)"},
+		},
+		{
+			name: "recovered",
+			lines: []string{
+				"panic: boom [recovered]",
+				"\tpanic: boom",
+				"",
+				"goroutine 7 [running]:",
+				"testing.tRunner.func1.2({0x5c8f20, 0x6371f0})",
+				"\t/usr/local/go/src/testing/testing.go:1545 +0x238",
+				"example.com/ex.TestX(0xc000007860?)",
+				"\t/src/ex/x_test.go:12 +0x25",
+				"created by testing.(*T).Run in goroutine 1",
+				"\t/usr/local/go/src/testing/testing.go:1648 +0x3ad",
+			},
+			value: "boom",
+			frames: []Frame{
+				{Func: "testing.tRunner.func1.2", File: "/usr/local/go/src/testing/testing.go", Line: 1545},
+				{Func: "example.com/ex.TestX", File: "/src/ex/x_test.go", Line: 12},
+				{Func: "created by testing.(*T).Run", File: "/usr/local/go/src/testing/testing.go", Line: 1648},
+			},
+		},
+		{
+			name: "goroutine",
+			lines: []string{
+				"panic: runtime error: index out of range [3] with length 2",
+				"",
+				"goroutine 9 [running]:",
+				"example.com/ex.work.func1()",
+				"\t/src/ex/x.go:30 +0x1d",
+				"created by example.com/ex.work in goroutine 8",
+				"\t/src/ex/x.go:28 +0x66",
+			},
+			value: "runtime error: index out of range [3] with length 2",
+			frames: []Frame{
+				{Func: "example.com/ex.work.func1", File: "/src/ex/x.go", Line: 30},
+				{Func: "created by example.com/ex.work", File: "/src/ex/x.go", Line: 28},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := outputEvents(key, tc.lines...).FindPanic()
+			if tc.value == "" {
+				if p != nil {
+					t.Fatalf("found panic %q", p.Value)
+				}
+				return
+			}
+			if p == nil {
+				t.Fatal("no panic found")
+			}
+			if p.Value != tc.value {
+				t.Errorf("value %q, want %q", p.Value, tc.value)
+			}
+			if len(p.Goroutines) != 1 {
+				t.Fatalf("%d goroutines, want 1", len(p.Goroutines))
+			}
+			if got := p.Goroutines[0].Frames; !reflect.DeepEqual(got, tc.frames) {
+				t.Errorf("frames\n%v\nwant\n%v", got, tc.frames)
+			}
+		})
+	}
+}
+
+func TestFrameInModule(t *testing.T) {
+	for _, tc := range []struct {
+		fn      string
+		modules []string
+		want    bool
+	}{
+		{"example.com/ex.TestX", []string{"example.com/ex"}, true},
+		{"example.com/ex/sub.f", []string{"example.com/ex"}, true},
+		{"example.com/exotic.f", []string{"example.com/ex"}, false},
+		{"created by example.com/ex.f", []string{"example.com/ex"}, true},
+		{"testing.tRunner", []string{"example.com/ex"}, false},
+		{"testing.tRunner", nil, false},
+		{"github.com/dep/pkg.F", nil, true},
+	} {
+		if got := (Frame{Func: tc.fn}).InModule(tc.modules); got != tc.want {
+			t.Errorf("InModule(%s, %v) = %v, want %v", tc.fn, tc.modules, got, tc.want)
+		}
+	}
+}
+
+func TestSubtestPanic(t *testing.T) {
+	for _, tc := range []struct {
+		fixture  string
+		statuses map[string]Status
+		causes   []string
+	}{
+		{
+			fixture: "panics.json",
+			statuses: map[string]Status{
+				"TestFirst":      StatusPass,
+				"TestPanics":     StatusFail,
+				"TestPanics/ok":  StatusPass,
+				"TestPanics/sub": StatusPanic,
+			},
+			causes: []string{"TestPanics/sub"},
+		},
+		{
+			fixture: "nested.json",
+			statuses: map[string]Status{
+				"TestNested":     StatusFail,
+				"TestNested/a":   StatusFail,
+				"TestNested/a/b": StatusPanic,
+			},
+			causes: []string{"TestNested/a/b"},
+		},
+		{
+			// the parent panics itself after a subtest failed
+			fixture: "selfpanic.json",
+			statuses: map[string]Status{
+				"TestParentPanics":     StatusPanic,
+				"TestParentPanics/bad": StatusFail,
+			},
+			causes: []string{"TestParentPanics", "TestParentPanics/bad"},
+		},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			tests := loadTests(t, tc.fixture)
+			var pkg string
+			for key := range tests.Tests {
+				pkg = key.Package
+			}
+			for test, want := range tc.statuses {
+				key := Key{Package: pkg, Test: test}
+				if got := tests.StatusOf(key); got != want {
+					t.Errorf("%s: %s, want %s", test, got, want)
+				}
+			}
+			// the parents of a panicking subtest are collateral
+			failed := tests.FindByStatus(FailureStatuses...).FilterCascadingFailures().FilterPackageResults()
+			var causes []string
+			for _, key := range failed.OrderedKeys() {
+				causes = append(causes, key.Test)
+			}
+			if !reflect.DeepEqual(causes, tc.causes) {
+				t.Errorf("root causes %v, want %v", causes, tc.causes)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// KeyPattern matches keys by their package.Test name, * matches any text. A
+// pattern matching a test matches its subtests too.
+type KeyPattern struct {
+	Pattern string
+	re      *regexp.Regexp
+}
+
+func NewKeyPattern(pattern string) KeyPattern {
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	return KeyPattern{Pattern: pattern, re: regexp.MustCompile("^" + expr + "$")}
+}
+
+// Match reports whether key or one of its parent tests matches p.
+func (p KeyPattern) Match(key Key) bool {
+	for ; key.Test != ""; key = key.Parent() {
+		if p.re.MatchString(key.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// QuarantineEntry is a line of a quarantine file.
+type QuarantineEntry struct {
+	KeyPattern
+	Owner   string
+	Ticket  string
+	Expires time.Time
+}
+
+// Expired reports whether e expired before now.
+func (e QuarantineEntry) Expired(now time.Time) bool {
+	return now.After(e.Expires.AddDate(0, 0, 1))
+}
+
+func (e QuarantineEntry) String() string {
+	return e.Owner + " " + e.Ticket + " until " + e.Expires.Format("2006-01-02")
+}
+
+// Quarantine is a list of known flaky tests, read from a file with a line per
+// entry:
+//
+//	# pattern                        owner    ticket    expires
+//	example.com/pkg.TestFlaky        @team    BUG-123   2025-12-31
+//	example.com/pkg/slow.TestIO/*    @other   BUG-456   2025-06-30
+type Quarantine struct {
+	Filename string
+	Entries  []QuarantineEntry
+}
+
+// ReadQuarantine reads the quarantine file filename.
+func ReadQuarantine(filename string) (*Quarantine, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	q := &Quarantine{Filename: filename}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s:%d: want pattern, owner, ticket and expiry date", filename, n)
+		}
+		expires, err := time.Parse("2006-01-02", fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid expiry date: %w", filename, n, err)
+		}
+		q.Entries = append(q.Entries, QuarantineEntry{
+			KeyPattern: NewKeyPattern(fields[0]),
+			Owner:      fields[1],
+			Ticket:     fields[2],
+			Expires:    expires,
+		})
+	}
+	return q, scanner.Err()
+}
+
+// Find returns the entry that quarantines key, nil if key isn't quarantined.
+// It is safe to call on a nil Quarantine.
+func (q *Quarantine) Find(key Key) *QuarantineEntry {
+	if q == nil {
+		return nil
+	}
+	for i, e := range q.Entries {
+		if e.Match(key) {
+			return &q.Entries[i]
+		}
+	}
+	return nil
+}
+
+// ReadyEntries returns the entries whose tests passed in each of the last n
+// runs, the current run in ts included.
+func (q *Quarantine) ReadyEntries(ts TestStorage, runs []HistoryRun, n int) []QuarantineEntry {
+	if n <= 0 {
+		return nil
+	}
+	runs = append([]HistoryRun{ts.HistoryRun(Flags{}, nil)}, runs...)
+	if len(runs) < n {
+		return nil
+	}
+	var ready []QuarantineEntry
+entries:
+	for _, e := range q.Entries {
+		for _, run := range runs[:n] {
+			seen := false
+			for _, t := range run.Tests {
+				if !e.Match(t.Key()) {
+					continue
+				}
+				if t.Status != StatusPass {
+					continue entries
+				}
+				seen = true
+			}
+			if !seen {
+				continue entries
+			}
+		}
+		ready = append(ready, e)
+	}
+	return ready
+}
+
+// PrintQuarantine prints the quarantined failures in ts, the expired entries
+// and the entries that are ready to be removed.
+func (ts TestStorage) PrintQuarantine(flags Flags) {
+	if ts.Quarantine == nil {
+		return
+	}
+	failed := ts.FindByStatus(StatusQuarantined)
+	if !flags.FailParents {
+		failed = failed.FilterCascadingFailures()
+	}
+
+	var expired []QuarantineEntry
+	now := time.Now()
+	for _, e := range ts.Quarantine.Entries {
+		if e.Expired(now) {
+			expired = append(expired, e)
+		}
+	}
+
+	var ready []QuarantineEntry
+	if history, err := OpenHistory(ts.Modules); err == nil {
+		if runs, err := history.Runs(); err == nil {
+			ready = ts.Quarantine.ReadyEntries(ts, runs, flags.QuarantineRuns)
+		}
+	}
+
+	if len(failed.Tests) == 0 && len(expired) == 0 && len(ready) == 0 {
+		return
+	}
+	hr := quarantineColor("════════════")
+	fmt.Println(hr, quarantineColorBold(statusNames[StatusQuarantined]), hr)
+	prefix := quarantineColor(fmt.Sprintf("%6s ", "QUAR"))
+	for _, key := range failed.OrderedKeys() {
+		if key.Test == "" {
+			// the package failed because of the tests listed
+			continue
+		}
+		text := ts.Links.LinkPackage(key.Package, packageColor(key.Package))
+		if key.Test != "" {
+			text += "." + ts.Links.LinkTest(key, testColor(key.Test))
+		}
+		if e := ts.Quarantine.Find(key); e != nil {
+			text += "  " + quarantineColor(e.String())
+		}
+		fmt.Println(prefix + text)
+	}
+	for _, e := range expired {
+		fmt.Println(timeoutColorBold("  expired") + "  " + e.Pattern + "  " + timeoutColor(e.String()))
+	}
+	for _, e := range ready {
+		fmt.Println(passColorBold("  ready to remove") + "  " + e.Pattern + "  " +
+			passColor(fmt.Sprintf("passed in each of the last %d runs", flags.QuarantineRuns)))
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyPattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		key     Key
+		want    bool
+	}{
+		{"example.com/a.TestA", Key{Package: "example.com/a", Test: "TestA"}, true},
+		{"example.com/a.TestA", Key{Package: "example.com/a", Test: "TestA/sub"}, true},
+		{"example.com/a.TestA", Key{Package: "example.com/a", Test: "TestAB"}, false},
+		{"example.com/a.TestA", Key{Package: "example.com/a"}, false},
+		{"example.com/a.TestA/*", Key{Package: "example.com/a", Test: "TestA/sub"}, true},
+		{"example.com/a.TestA/*", Key{Package: "example.com/a", Test: "TestA"}, false},
+		{"example.com/*.TestIO", Key{Package: "example.com/b/c", Test: "TestIO"}, true},
+		{"example.com/a.Test(A)", Key{Package: "example.com/a", Test: "TestA"}, false},
+	} {
+		if got := NewKeyPattern(tc.pattern).Match(tc.key); got != tc.want {
+			t.Errorf("%s matches %v = %v, want %v", tc.pattern, tc.key, got, tc.want)
+		}
+	}
+}
+
+// writeFile writes content to name in a temporary directory and returns its
+// path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestReadQuarantine(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    []string
+		err     string
+	}{
+		{
+			name: "entries",
+			content: "# pattern owner ticket expires\n\n" +
+				"example.com/a.TestFlaky  @team  BUG-1  2025-12-31\n" +
+				"  example.com/b.TestIO/*  @other  BUG-2  2025-06-30  \n",
+			want: []string{
+				"example.com/a.TestFlaky @team BUG-1 until 2025-12-31",
+				"example.com/b.TestIO/* @other BUG-2 until 2025-06-30",
+			},
+		},
+		{name: "empty", content: "# nothing yet\n"},
+		{
+			name:    "missing field",
+			content: "example.com/a.TestFlaky @team 2025-12-31\n",
+			err:     ":1: want pattern, owner, ticket and expiry date",
+		},
+		{
+			name:    "invalid date",
+			content: "# header\nexample.com/a.TestFlaky @team BUG-1 31.12.2025\n",
+			err:     ":2: invalid expiry date",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := ReadQuarantine(writeFile(t, ".tgo-quarantine", tc.content))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range q.Entries {
+				got = append(got, e.Pattern+" "+e.String())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if _, err := ReadQuarantine(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing file: got no error")
+	}
+}
+
+func TestQuarantineExpired(t *testing.T) {
+	e := QuarantineEntry{Expires: time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)}
+	for _, tc := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{time.Date(2025, 6, 29, 12, 0, 0, 0, time.UTC), false},
+		{time.Date(2025, 6, 30, 23, 59, 0, 0, time.UTC), false},
+		{time.Date(2025, 7, 1, 0, 1, 0, 0, time.UTC), true},
+	} {
+		if got := e.Expired(tc.now); got != tc.want {
+			t.Errorf("Expired(%v) = %v, want %v", tc.now, got, tc.want)
+		}
+	}
+}
+
+func TestQuarantineStatus(t *testing.T) {
+	flaky := Key{Package: "example.com/a", Test: "TestFlaky"}
+	other := Key{Package: "example.com/a", Test: "TestOther"}
+	tests := NewTestStorage(&RunContext{Quarantine: &Quarantine{
+		Entries: []QuarantineEntry{{KeyPattern: NewKeyPattern("example.com/a.TestFlaky")}},
+	}})
+	for _, key := range []Key{flaky, other} {
+		for _, e := range endedEvents(key, ActionFail, 0, "    a_test.go:1: bad") {
+			tests.Append(e)
+		}
+	}
+	if got := tests.StatusOf(flaky); got != StatusQuarantined {
+		t.Errorf("%v: got %v, want %v", flaky, got, StatusQuarantined)
+	}
+	if got := tests.StatusOf(other); got != StatusFail {
+		t.Errorf("%v: got %v, want %v", other, got, StatusFail)
+	}
+}
+
+func TestReadyEntries(t *testing.T) {
+	key := Key{Package: "example.com/a", Test: "TestFlaky"}
+	q := &Quarantine{Entries: []QuarantineEntry{
+		{KeyPattern: NewKeyPattern("example.com/a.TestFlaky")},
+		{KeyPattern: NewKeyPattern("example.com/a.TestGone")},
+	}}
+	passed := HistoryRun{Tests: []HistoryTest{{Package: key.Package, Test: key.Test, Status: StatusPass}}}
+	failed := HistoryRun{Tests: []HistoryTest{{Package: key.Package, Test: key.Test, Status: StatusFail}}}
+	for _, tc := range []struct {
+		name string
+		runs []HistoryRun
+		n    int
+		want []string
+	}{
+		{"disabled", []HistoryRun{passed, passed}, 0, nil},
+		{"too few runs", []HistoryRun{passed}, 3, nil},
+		{"passed", []HistoryRun{passed, passed, failed}, 3, []string{"example.com/a.TestFlaky"}},
+		{"failed", []HistoryRun{passed, failed}, 3, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tests := NewTestStorage(nil)
+			for _, e := range endedEvents(key, ActionPass, 0) {
+				tests.Append(e)
+			}
+			var got []string
+			for _, e := range q.ReadyEntries(tests, tc.runs, tc.n) {
+				got = append(got, e.Pattern)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOnlyExpectedFailures(t *testing.T) {
+	pkg := "example.com/a"
+	quarantine := &Quarantine{
+		Entries: []QuarantineEntry{{KeyPattern: NewKeyPattern("example.com/a.TestFlaky")}},
+	}
+	for _, tc := range []struct {
+		name   string
+		events []Events
+		want   bool
+	}{
+		// go test fails before running any test, ie. with an invalid flag
+		{name: "no events"},
+		{
+			name: "quarantined",
+			events: []Events{
+				endedEvents(Key{Package: pkg, Test: "TestFlaky"}, ActionFail, 0, "    a_test.go:1: bad"),
+				endedEvents(Key{Package: pkg, Test: "TestOK"}, ActionPass, 0),
+				endedEvents(Key{Package: pkg}, ActionFail, 0),
+			},
+			want: true,
+		},
+		{
+			name: "other failure",
+			events: []Events{
+				endedEvents(Key{Package: pkg, Test: "TestFlaky"}, ActionFail, 0, "    a_test.go:1: bad"),
+				endedEvents(Key{Package: pkg, Test: "TestBroken"}, ActionFail, 0, "    a_test.go:5: bad"),
+				endedEvents(Key{Package: pkg}, ActionFail, 0),
+			},
+		},
+		{
+			name: "build failure",
+			events: []Events{
+				endedEvents(Key{Package: pkg}, ActionFail, 0, "a.go:1:1: syntax error"),
+			},
+		},
+		{
+			name: "no failures",
+			events: []Events{
+				endedEvents(Key{Package: pkg, Test: "TestOK"}, ActionPass, 0),
+				endedEvents(Key{Package: pkg}, ActionPass, 0),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tests := NewTestStorage(&RunContext{Quarantine: quarantine})
+			for _, es := range tc.events {
+				for _, e := range es {
+					tests.Append(e)
+				}
+			}
+			tests.ResolveStatuses()
+			if got := tests.OnlyExpectedFailures(Flags{}); got != tc.want {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Race is a data race report from the race detector.
+type Race struct {
+	Accesses []RaceAccess
+}
+
+// RaceAccess is one of the conflicting memory accesses of a race.
+type RaceAccess struct {
+	Op     string // ie. "Read", "Previous write"
+	Frames []Frame
+}
+
+// Location returns the top frame of the access in modules, or the top frame if
+// none of them are.
+func (a RaceAccess) Location(modules []string) Frame {
+	for _, f := range a.Frames {
+		if f.InModule(modules) {
+			return f
+		}
+	}
+	if len(a.Frames) > 0 {
+		return a.Frames[0]
+	}
+	return Frame{}
+}
+
+// Fingerprint identifies the race by the locations of its first pair of
+// accesses so the same race reported by different tests compares equal.
+func (r Race) Fingerprint(modules []string) string {
+	var locs []string
+	for i, a := range r.Accesses {
+		if i == 2 {
+			break
+		}
+		locs = append(locs, a.Location(modules).String())
+	}
+	sort.Strings(locs)
+	return strings.Join(locs, " | ")
+}
+
+var (
+	raceAccessRe    = regexp.MustCompile(`^(\S.*?) at 0x[0-9a-f]+ by .*:$`)
+	raceFrameFileRe = regexp.MustCompile(`^      (.*\.(?:go|s)):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+const (
+	raceHeader   = "WARNING: DATA RACE"
+	raceSep      = "=================="
+	raceDetected = "race detected during execution of test"
+)
+
+// FindRaces returns the data race reports in the output of es.
+func (es Events) FindRaces() []Race {
+	var (
+		races  []Race
+		race   *Race
+		access *RaceAccess
+	)
+	lines := es.outputLines()
+	for i, line := range lines {
+		switch {
+		case line == raceHeader:
+			race = &Race{}
+		case race == nil:
+		case line == raceSep:
+			races = append(races, *race)
+			race, access = nil, nil
+		case raceAccessRe.MatchString(line):
+			race.Accesses = append(race.Accesses, RaceAccess{
+				Op: raceAccessRe.FindStringSubmatch(line)[1],
+			})
+			access = &race.Accesses[len(race.Accesses)-1]
+		case line == "":
+			access = nil
+		case access != nil && strings.HasPrefix(line, "  ") && i+1 < len(lines):
+			m := raceFrameFileRe.FindStringSubmatch(lines[i+1])
+			if m == nil {
+				continue
+			}
+			fn := strings.TrimSpace(line)
+			if j := strings.LastIndex(fn, "("); j > 0 {
+				fn = fn[:j]
+			}
+			n, _ := strconv.Atoi(m[2])
+			access.Frames = append(access.Frames, Frame{Func: fn, File: m[1], Line: n})
+		}
+	}
+	return races
+}
+
+// HasRace reports whether the race detector failed es, either with a race
+// report or because a race was detected while it was running.
+func (es Events) HasRace() bool {
+	for _, e := range es {
+		if e.Action != ActionOutput {
+			continue
+		}
+		output := strings.TrimSpace(e.Output)
+		if output == raceHeader || strings.HasSuffix(output, raceDetected) {
+			return true
+		}
+	}
+	return false
+}
+
+// UniqueRace is a race and the tests that reported it.
+type UniqueRace struct {
+	Race Race
+	Keys []Key
+}
+
+// FindUniqueRaces returns the races reported in ts, deduplicated by their
+// fingerprint and ordered by the number of tests that reported them.
+func (ts TestStorage) FindUniqueRaces() []*UniqueRace {
+	var races []*UniqueRace
+	byFingerprint := make(map[string]*UniqueRace)
+	for _, key := range ts.OrderedKeys() {
+		for _, race := range ts.Tests[key].FindRaces() {
+			fp := race.Fingerprint(ts.Modules)
+			ur, ok := byFingerprint[fp]
+			if !ok {
+				ur = &UniqueRace{Race: race}
+				byFingerprint[fp] = ur
+				races = append(races, ur)
+			}
+			if len(ur.Keys) == 0 || ur.Keys[len(ur.Keys)-1] != key {
+				ur.Keys = append(ur.Keys, key)
+			}
+		}
+	}
+	sort.SliceStable(races, func(i, j int) bool {
+		return len(races[i].Keys) > len(races[j].Keys)
+	})
+	return races
+}
+
+// PrintRaces prints every unique race in ts once, with the locations of the
+// racing accesses and the tests that triggered it.
+func (ts TestStorage) PrintRaces() {
+	statusColor := statusColors[StatusRace]
+	statusBold := statusColorsBold[StatusRace]
+	hr := statusColor("════════════")
+
+	races := ts.FindUniqueRaces()
+	reported := make(map[Key]bool)
+
+	fmt.Println(hr, statusBold("RACES"), hr)
+	for i, ur := range races {
+		fmt.Println(statusBold(fmt.Sprintf("%6s ", fmt.Sprintf("#%d", i+1))) +
+			statusColor(fmt.Sprintf("reported by %d tests", len(ur.Keys))))
+		for _, a := range ur.Race.Accesses {
+			loc := a.Location(ts.Modules)
+			fmt.Printf("       %-16s %s  %s\n",
+				strings.ToLower(a.Op), testColor(loc.Func), fmt.Sprintf("%s:%d", loc.File, loc.Line))
+		}
+		for _, key := range ur.Keys {
+			reported[key] = true
+			name := packageColor(key.Package)
+			if key.Test != "" {
+				name += "." + testColor(key.Test)
+			}
+			fmt.Println("         " + name)
+		}
+	}
+
+	var others []Key
+	for _, key := range ts.FindByStatus(StatusRace).OrderedKeys() {
+		if !reported[key] && key.Test != "" {
+			others = append(others, key)
+		}
+	}
+	if len(others) > 0 {
+		fmt.Println(statusColor("       race detected, reported elsewhere:"))
+		for _, key := range others {
+			fmt.Println("         " + packageColor(key.Package) + "." + testColor(key.Test))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+var raceReport = []string{
+	"==================",
+	"WARNING: DATA RACE",
+	"Read at 0x000000834568 by goroutine 8:",
+	"  example.com/ex/race.inc()",
+	"      /tmp/race/race_test.go:10 +0x75",
+	"  example.com/ex/race.racy.func1()",
+	"      /tmp/race/race_test.go:18 +0x69",
+	"",
+	"Previous write at 0x000000834568 by goroutine 9:",
+	"  sync/atomic.AddInt32()",
+	"      /usr/local/go/src/runtime/race_amd64.s:281 +0xb",
+	"  example.com/ex/race.inc()",
+	"      /tmp/race/race_test.go:11 +0x8d",
+	"",
+	"Goroutine 8 (running) created at:",
+	"  example.com/ex/race.racy()",
+	"      /tmp/race/race_test.go:16 +0x56",
+	"==================",
+	"    testing.go:1865: race detected during execution of test",
+}
+
+func TestFindRaces(t *testing.T) {
+	key := Key{Package: "example.com/ex/race", Test: "TestRace"}
+	races := outputEvents(key, raceReport...).FindRaces()
+	want := []Race{{Accesses: []RaceAccess{
+		{Op: "Read", Frames: []Frame{
+			{Func: "example.com/ex/race.inc", File: "/tmp/race/race_test.go", Line: 10},
+			{Func: "example.com/ex/race.racy.func1", File: "/tmp/race/race_test.go", Line: 18},
+		}},
+		{Op: "Previous write", Frames: []Frame{
+			{Func: "sync/atomic.AddInt32", File: "/usr/local/go/src/runtime/race_amd64.s", Line: 281},
+			{Func: "example.com/ex/race.inc", File: "/tmp/race/race_test.go", Line: 11},
+		}},
+	}}}
+	if !reflect.DeepEqual(races, want) {
+		t.Fatalf("got %+v, want %+v", races, want)
+	}
+	modules := []string{"example.com/ex"}
+	if got, want := races[0].Accesses[1].Location(modules).Line, 11; got != want {
+		t.Errorf("Location line = %d, want %d", got, want)
+	}
+	if got, want := races[0].Fingerprint(modules), "example.com/ex/race.inc  /tmp/race/race_test.go:10 | example.com/ex/race.inc  /tmp/race/race_test.go:11"; got != want {
+		t.Errorf("Fingerprint = %q, want %q", got, want)
+	}
+}
+
+func TestHasRace(t *testing.T) {
+	key := Key{Package: "example.com/ex/race", Test: "TestRace"}
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		want  bool
+	}{
+		{"report", raceReport, true},
+		{"detected", []string{"    testing.go:1865: race detected during execution of test"}, true},
+		{"none", []string{"    x_test.go:10: WARNING: DATA RACE ahead"}, false},
+	} {
+		if got := outputEvents(key, tc.lines...).HasRace(); got != tc.want {
+			t.Errorf("%s: HasRace() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFindUniqueRaces(t *testing.T) {
+	tests := NewTestStorage(&RunContext{Modules: []string{"example.com/ex"}})
+	a := Key{Package: "example.com/ex/race", Test: "TestA"}
+	b := Key{Package: "example.com/ex/race", Test: "TestB"}
+	for _, key := range []Key{a, b} {
+		for _, e := range outputEvents(key, raceReport...) {
+			tests.Append(e)
+		}
+	}
+	races := tests.FindUniqueRaces()
+	if len(races) != 1 {
+		t.Fatalf("got %d races, want 1", len(races))
+	}
+	if want := []Key{a, b}; !reflect.DeepEqual(races[0].Keys, want) {
+		t.Errorf("Keys = %v, want %v", races[0].Keys, want)
+	}
+}
+
+func TestRaceStatus(t *testing.T) {
+	tests := loadTests(t, "race.json")
+	pkg := "example.com/ex/race"
+	for test, want := range map[string]Status{
+		"TestRaceA": StatusRace,
+		"TestRaceB": StatusRace,
+		"TestOK":    StatusPass,
+	} {
+		if got := tests.StatusOf(Key{Package: pkg, Test: test}); got != want {
+			t.Errorf("%s: %s, want %s", test, got, want)
+		}
+	}
+	if got := len(tests.FindUniqueRaces()); got != 2 {
+		t.Errorf("got %d races, want 2", got)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+)
+
+// OutputLine is a line of test output ready to be printed.
+type OutputLine struct {
+	Event Event // the event the line was rendered from
+	Text  string
+}
+
+// renderer renders a block of output starting at es[0]. It returns the
+// rendered lines and the number of events consumed, or 0 if es doesn't start
+// with a block the renderer recognises.
+type renderer func(flags Flags, es Events, textColor func(a ...interface{}) string) ([]OutputLine, int)
+
+// renderers are tried in order at every output event, unrecognised output is
+// printed as is.
+var renderers = []renderer{
+	renderTestify,
+	renderCmpDiff,
+	renderExample,
+	renderLog,
+}
+
+// Render renders the output of es for printing. Blank lines are dropped at
+// verbosity V3 and below unless a renderer uses them.
+func (es Events) Render(flags Flags, textColor func(a ...interface{}) string) []OutputLine {
+	var lines []OutputLine
+loop:
+	for i := 0; i < len(es); {
+		if flags.V <= V3 {
+			for _, r := range renderers {
+				if rendered, n := r(flags, es[i:], textColor); n > 0 {
+					lines = append(lines, rendered...)
+					i += n
+					continue loop
+				}
+			}
+		}
+		if flags.V <= V3 && strings.TrimSpace(es[i].Output) == "" {
+			i++
+			continue
+		}
+		lines = append(lines, OutputLine{
+			Event: es[i],
+			Text:  textColor(strings.TrimSuffix(es[i].Output, "\n")),
+		})
+		i++
+	}
+	return lines
+}
+
+// truncateWidth is the width long values are truncated to in rendered output.
+func (f Flags) truncateWidth() int {
+	switch {
+	case f.V >= V3:
+		return 0
+	case f.V >= V2:
+		return 500
+	default:
+		return 120
+	}
+}
+
+// leadingSpace returns the leading white space of s.
+func leadingSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
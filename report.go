@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Report is the machine readable result of a run, written to the file set by
+// TGO_REPORT.
+type Report struct {
+	Time  time.Time
+	Args  []string
+	Tests []ReportTest
+}
+
+// ReportTest is the result of a single key in a Report.
+type ReportTest struct {
+	Package  string
+	Test     string `json:",omitempty"`
+	Status   Status
+	Elapsed  float64
+	Note     string          `json:",omitempty"`
+	Message  string          `json:",omitempty"`
+	Rerun    string          `json:",omitempty"`
+	Owners   []string        `json:",omitempty"`
+	Attempts []ReportAttempt `json:",omitempty"`
+}
+
+// Report returns the results in ts as a Report.
+func (ts TestStorage) Report(flags Flags, argv []string) Report {
+	report := Report{
+		Time: time.Now(),
+		Args: ts.Redactor.RedactArgs(argv),
+	}
+	rerun := make(map[Key]bool)
+	rerunKeys := ts.RerunKeys(flags)
+	for _, key := range rerunKeys {
+		rerun[key] = true
+	}
+	ts.CodeOwners.Load(rerunKeys)
+	for _, key := range ts.OrderedKeys() {
+		events := ts.Tests[key]
+		status := ts.StatusOf(key)
+		t := ReportTest{
+			Package: key.Package,
+			Test:    key.Test,
+			Status:  status,
+			Note:    ts.Note(key),
+		}
+		if e := events.FindFirstByAction(EndingActions...); e != nil {
+			t.Elapsed = e.Elapsed
+		}
+		if FailureStatuses.Any(status) {
+			t.Message = events.FailureMessage()
+		}
+		if rerun[key] {
+			switch {
+			case key.Test != "":
+				t.Rerun = ts.RerunCommand(flags, argv, key.Package, RunPattern(key.Test))
+			case key.Package != "":
+				t.Rerun = ts.RerunCommand(flags, argv, key.Package, "")
+			}
+			t.Owners = ts.CodeOwners.KeyOwners(key)
+		}
+		t.Attempts = ts.Retries.ReportAttempts(key)
+		report.Tests = append(report.Tests, t)
+	}
+	return report
+}
+
+// WriteReport writes the results in ts as JSON to filename.
+func (ts TestStorage) WriteReport(flags Flags, argv []string, filename string) error {
+	data, err := json.MarshalIndent(ts.Report(flags, argv), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RunPattern returns a -run pattern that matches exactly test, anchored and
+// escaped per subtest level.
+func RunPattern(test string) string {
+	parts := strings.Split(test, "/")
+	for i, p := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(p) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+// RunPatterns returns the -run patterns that match exactly tests, in the order
+// of tests. go test matches each subtest level separately, so only tests with
+// the same parent are combined into one pattern with an alternation of their
+// names, other tests need a pattern, and a go test run, of their own.
+func RunPatterns(tests []string) []string {
+	var parents []string
+	names := make(map[string][]string)
+	for _, test := range tests {
+		parent, name := "", test
+		if i := strings.LastIndex(test, "/"); i >= 0 {
+			parent, name = test[:i], test[i+1:]
+		}
+		if _, ok := names[parent]; !ok {
+			parents = append(parents, parent)
+		}
+		names[parent] = append(names[parent], regexp.QuoteMeta(name))
+	}
+	var patterns []string
+	for _, parent := range parents {
+		last := "^" + names[parent][0] + "$"
+		if len(names[parent]) > 1 {
+			last = "^(" + strings.Join(names[parent], "|") + ")$"
+		}
+		if parent == "" {
+			patterns = append(patterns, last)
+			continue
+		}
+		patterns = append(patterns, RunPattern(parent)+"/"+last)
+	}
+	return patterns
+}
+
+// testValueFlags are the go test and build flags that take a value, which
+// can be passed as the next argument.
+var testValueFlags = map[string]bool{
+	"asmflags": true, "bench": true, "benchtime": true, "blockprofile": true,
+	"blockprofilerate": true, "C": true, "count": true, "covermode": true,
+	"coverpkg": true, "coverprofile": true, "cpu": true, "cpuprofile": true,
+	"exec": true, "fuzz": true, "fuzzminimizetime": true, "fuzztime": true,
+	"gccgoflags": true, "gcflags": true, "installsuffix": true, "ldflags": true,
+	"list": true, "memprofile": true, "memprofilerate": true, "mod": true,
+	"modfile": true, "mutexprofile": true, "mutexprofilefraction": true,
+	"o": true, "outputdir": true, "overlay": true, "p": true, "parallel": true,
+	"pgo": true, "pkgdir": true, "run": true, "shuffle": true, "skip": true,
+	"tags": true, "timeout": true, "toolexec": true, "trace": true, "vet": true,
+}
+
+// SplitArgs splits the go test arguments argv into flags, packages and the
+// arguments after -args that go to the test binaries. Flag values passed as
+// the next argument are joined to their flag with "=".
+func SplitArgs(argv []string) (flags, packages, binaryArgs []string) {
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		switch {
+		case arg == "-args" || arg == "--args":
+			return flags, packages, argv[i:]
+		case !strings.HasPrefix(arg, "-"):
+			packages = append(packages, arg)
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if testValueFlags[name] && !hasValue && i+1 < len(argv) {
+			arg = "-" + name + "=" + argv[i+1]
+			i++
+		}
+		flags = append(flags, arg)
+	}
+	return flags, packages, nil
+}
+
+// rerunFlags are the go test flags kept when rerunning a test.
+var rerunFlags = map[string]bool{
+	"race":     true,
+	"msan":     true,
+	"asan":     true,
+	"short":    true,
+	"tags":     true,
+	"count":    true,
+	"cpu":      true,
+	"timeout":  true,
+	"ldflags":  true,
+	"gcflags":  true,
+	"mod":      true,
+	"shuffle":  true,
+	"parallel": true,
+}
+
+// RerunArgs returns the flags from the go test arguments argv that affect how
+// a test runs. -count=1 is added unless argv sets a count so reruns are never
+// cached.
+func RerunArgs(argv []string) []string {
+	var args []string
+	hasCount := false
+	flags, _, _ := SplitArgs(argv)
+	for _, arg := range flags {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !rerunFlags[name] {
+			continue
+		}
+		if name == "count" {
+			hasCount = true
+		}
+		args = append(args, arg)
+	}
+	if !hasCount {
+		args = append(args, "-count=1")
+	}
+	return args
+}
+
+var shuffleRe = regexp.MustCompile(`^-test\.shuffle (\d+)$`)
+
+// ShuffleSeed returns the -shuffle seed the test binary of pkg printed, ""
+// if its tests weren't shuffled.
+func (ts TestStorage) ShuffleSeed(pkg string) string {
+	for _, line := range ts.Tests[Key{Package: pkg}].outputLines() {
+		if m := shuffleRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// RerunCommand returns the go test command that runs the tests of pkg matching
+// the -run pattern run again, all of pkg if run is "", keeping the flags from
+// argv that affect how they run.
+func (ts TestStorage) RerunCommand(flags Flags, argv []string, pkg, run string) string {
+	args := []string{flags.Bin, "test"}
+	for _, arg := range RerunArgs(argv) {
+		if strings.HasPrefix(strings.TrimLeft(arg, "-"), "shuffle") {
+			if seed := ts.ShuffleSeed(pkg); seed != "" {
+				arg = "-shuffle=" + seed
+			}
+		}
+		args = append(args, arg)
+	}
+	if run != "" {
+		args = append(args, "-run", run)
+	}
+	args = append(args, pkg)
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for a POSIX shell if needed.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=./,:@+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RerunKeys returns the keys of the failed and unfinished tests in ts. Package
+// keys are only included for packages that failed without a failing test.
+func (ts TestStorage) RerunKeys(flags Flags) []Key {
+	statuses := append(Statuses{StatusTimeout, StatusNone}, FailureStatuses...)
+	tests := ts.FindByStatus(statuses...)
+	if !flags.FailParents {
+		tests = tests.FilterCascadingFailures()
+	}
+	withTests := make(map[string]bool)
+	for key := range tests.Tests {
+		if key.Test != "" {
+			withTests[key.Package] = true
+		}
+	}
+	var keys []Key
+	for _, key := range tests.OrderedKeys() {
+		if key.Test == "" && withTests[key.Package] {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// RerunTests returns the tests of keys to rerun by package, in the order of
+// keys. Package keys are left out, and so are tests whose subtests are
+// included since running a subtest runs its parents too.
+func RerunTests(keys []Key) (packages []string, tests map[string][]string) {
+	tests = make(map[string][]string)
+keys:
+	for _, key := range keys {
+		if key.Package == "" || key.Test == "" {
+			continue
+		}
+		for _, other := range keys {
+			if other.IsSubtestOf(key) {
+				continue keys
+			}
+		}
+		if _, ok := tests[key.Package]; !ok {
+			packages = append(packages, key.Package)
+		}
+		tests[key.Package] = append(tests[key.Package], key.Test)
+	}
+	return packages, tests
+}
+
+// PrintRerun prints the commands that rerun the tests of keys, one per
+// package and -run pattern.
+func (ts TestStorage) PrintRerun(flags Flags, argv []string, keys []Key) {
+	packages, tests := RerunTests(keys)
+	var commands []string
+	for _, pkg := range packages {
+		for _, run := range RunPatterns(tests[pkg]) {
+			commands = append(commands, ts.RerunCommand(flags, argv, pkg, run))
+		}
+	}
+	if len(commands) == 0 {
+		return
+	}
+	hr := failColor("════════════")
+	fmt.Println(hr, failColorBold("RERUN"), hr)
+	for i, command := range commands {
+		if flags.V < V2 && i == 10 {
+			fmt.Printf("  … %d more, TGO_V=2 shows all\n", len(commands)-i)
+			break
+		}
+		fmt.Println("  " + command)
+	}
+}
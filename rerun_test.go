@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunPatterns(t *testing.T) {
+	for _, tc := range []struct {
+		tests []string
+		want  []string
+	}{
+		{nil, nil},
+		{[]string{"TestA"}, []string{"^TestA$"}},
+		{[]string{"TestA/sub.1"}, []string{`^TestA$/^sub\.1$`}},
+		{[]string{"TestA", "TestB"}, []string{"^(TestA|TestB)$"}},
+		{[]string{"TestA/x", "TestA/y", "TestB"}, []string{"^TestA$/^(x|y)$", "^TestB$"}},
+		{[]string{"TestA/x", "TestB/y"}, []string{"^TestA$/^x$", "^TestB$/^y$"}},
+		{[]string{"TestA/x", "TestB", "TestA/x/z"}, []string{"^TestA$/^x$", "^TestB$", "^TestA$/^x$/^z$"}},
+	} {
+		if got := RunPatterns(tc.tests); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("RunPatterns(%q) = %q, want %q", tc.tests, got, tc.want)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	for _, tc := range []struct {
+		argv                        []string
+		flags, packages, binaryArgs []string
+	}{
+		{nil, nil, nil, nil},
+		{[]string{"-v", "./..."}, []string{"-v"}, []string{"./..."}, nil},
+		{
+			[]string{"-run", "TestX", "-coverprofile", "c.out", "./a", "./b"},
+			[]string{"-run=TestX", "-coverprofile=c.out"}, []string{"./a", "./b"}, nil,
+		},
+		{
+			[]string{"-race", "./...", "-args", "-update", "x"},
+			[]string{"-race"}, []string{"./..."}, []string{"-args", "-update", "x"},
+		},
+	} {
+		flags, packages, binaryArgs := SplitArgs(tc.argv)
+		if !reflect.DeepEqual(flags, tc.flags) || !reflect.DeepEqual(packages, tc.packages) || !reflect.DeepEqual(binaryArgs, tc.binaryArgs) {
+			t.Errorf("SplitArgs(%q) = %q, %q, %q, want %q, %q, %q", tc.argv, flags, packages, binaryArgs, tc.flags, tc.packages, tc.binaryArgs)
+		}
+	}
+}
+
+func TestRerunArgs(t *testing.T) {
+	for _, tc := range []struct {
+		argv []string
+		want []string
+	}{
+		{nil, []string{"-count=1"}},
+		{[]string{"-race", "-v", "./..."}, []string{"-race", "-count=1"}},
+		{[]string{"-tags", "integration", "-count=3", "-run", "TestX"}, []string{"-tags=integration", "-count=3"}},
+		{[]string{"--timeout=5m", "-shuffle", "on"}, []string{"--timeout=5m", "-shuffle=on", "-count=1"}},
+	} {
+		if got := RerunArgs(tc.argv); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("RerunArgs(%q) = %q, want %q", tc.argv, got, tc.want)
+		}
+	}
+}
+
+func TestRerunTests(t *testing.T) {
+	keys := []Key{
+		{Package: ""},
+		{Package: "example.com/build"},
+		{Package: "example.com/a", Test: "TestOwn"},
+		{Package: "example.com/a", Test: "TestOwn/bad"},
+		{Package: "example.com/a", Test: "TestOther"},
+		{Package: "example.com/b", Test: "TestB"},
+	}
+	packages, tests := RerunTests(keys)
+	if want := []string{"example.com/a", "example.com/b"}; !reflect.DeepEqual(packages, want) {
+		t.Errorf("packages %q, want %q", packages, want)
+	}
+	want := map[string][]string{
+		"example.com/a": {"TestOwn/bad", "TestOther"},
+		"example.com/b": {"TestB"},
+	}
+	if !reflect.DeepEqual(tests, want) {
+		t.Errorf("tests %q, want %q", tests, want)
+	}
+}
+
+func TestRerunCommand(t *testing.T) {
+	tests := NewTestStorage(nil)
+	for _, e := range outputEvents(Key{Package: "example.com/a"}, "-test.shuffle 42") {
+		tests.Append(e)
+	}
+	flags := Flags{Bin: "go"}
+	argv := []string{"-shuffle=on", "./..."}
+	for _, tc := range []struct {
+		pkg  string
+		run  string
+		want string
+	}{
+		{"example.com/a", "^(TestA|TestB)$", "go test -shuffle=42 -count=1 -run '^(TestA|TestB)$' example.com/a"},
+		{"example.com/b", "", "go test -shuffle=on -count=1 example.com/b"},
+	} {
+		if got := tests.RerunCommand(flags, argv, tc.pkg, tc.run); got != tc.want {
+			t.Errorf("got %s, want %s", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Retries are the events of each retry attempt of a key, in order.
+type Retries map[Key][]Events
+
+// PassedOn returns the retry attempt key passed on, 0 if it never passed.
+func (r Retries) PassedOn(key Key) int {
+	for i, events := range r[key] {
+		if events.Status() == StatusPass {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// readEvents reads a go test -json stream into a TestStorage for rc.
+func readEvents(r io.Reader, rc *RunContext) TestStorage {
+	tests := NewTestStorage(rc)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		tests.Append(rc.Redactor.RedactEvent(e))
+	}
+	tests.ResolveStatuses()
+	return tests
+}
+
+// RetryArgs returns the flags from the go test arguments argv that a retry
+// runs with. Unlike RerunArgs a count from argv is replaced with -count=1, an
+// attempt runs each test once.
+func RetryArgs(argv []string) []string {
+	var args []string
+	for _, arg := range RerunArgs(argv) {
+		if name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "="); name != "count" {
+			args = append(args, arg)
+		}
+	}
+	return append(args, "-count=1")
+}
+
+// Retry runs the failed and unfinished tests in ts again, up to flags.Retries
+// times until they pass. Tests passing on a retry are flaky.
+func (ts TestStorage) Retry(ctx context.Context, flags Flags, argv []string) Retries {
+	r := make(Retries)
+	keys := ts.RerunKeys(flags)
+	for attempt := 1; attempt <= flags.Retries && len(keys) > 0; attempt++ {
+		fmt.Println(timeColor(fmt.Sprintf("retrying %d failed tests, attempt %d of %d", len(keys), attempt, flags.Retries)))
+		stdout, wait := goTest(ctx, flags.Bin, LastFailedArgs(keys, RetryArgs(argv)))
+		attemptTests := readEvents(stdout, &RunContext{Modules: ts.Modules, Redactor: ts.Redactor})
+		_ = wait()
+		for key, events := range attemptTests.Tests {
+			r[key] = append(r[key], events)
+		}
+		var failed []Key
+		for _, key := range keys {
+			if attemptTests.StatusOf(key) != StatusPass {
+				failed = append(failed, key)
+			}
+		}
+		keys = failed
+	}
+	return r
+}
+
+// ReportAttempt is a retry attempt of a test in a Report.
+type ReportAttempt struct {
+	Status  Status
+	Elapsed float64
+	Output  string `json:",omitempty"`
+}
+
+// ReportAttempts returns the retry attempts of key for a Report.
+func (r Retries) ReportAttempts(key Key) []ReportAttempt {
+	var attempts []ReportAttempt
+	for _, events := range r[key] {
+		a := ReportAttempt{
+			Status: events.Status(),
+			Output: strings.Join(events.Compact().outputLines(), "\n"),
+		}
+		if e := events.FindFirstByAction(EndingActions...); e != nil {
+			a.Elapsed = e.Elapsed
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts
+}
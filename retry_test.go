@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// endedEvents returns the events of key ending with action after elapsed
+// seconds.
+func endedEvents(key Key, action Action, elapsed float64, lines ...string) Events {
+	es := outputEvents(key, lines...)
+	return append(es, Event{Action: action, Package: key.Package, Test: key.Test, Elapsed: elapsed})
+}
+
+func TestReadEvents(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"Action":"run","Package":"example.com/a","Test":"TestA"}`,
+		`not json`,
+		`{"Action":"output","Package":"example.com/a","Test":"TestA","Output":"token=s3cret\n"}`,
+		`{"Action":"fail","Package":"example.com/a","Test":"TestA","Elapsed":0.5}`,
+	}, "\n")
+	redactor, err := NewRedactor(false, `s3cret`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := readEvents(strings.NewReader(stream), &RunContext{Redactor: redactor})
+	key := Key{Package: "example.com/a", Test: "TestA"}
+	if got := tests.StatusOf(key); got != StatusFail {
+		t.Errorf("status %v, want %v", got, StatusFail)
+	}
+	if got := strings.Join(tests.Tests[key].outputLines(), "\n"); strings.Contains(got, "s3cret") {
+		t.Errorf("output %q is not redacted", got)
+	}
+}
+
+func TestRetriesPassedOn(t *testing.T) {
+	key := Key{Package: "example.com/a", Test: "TestA"}
+	for _, tc := range []struct {
+		name     string
+		attempts []Events
+		want     int
+	}{
+		{"not retried", nil, 0},
+		{"first retry", []Events{endedEvents(key, ActionPass, 0)}, 1},
+		{"second retry", []Events{endedEvents(key, ActionFail, 0), endedEvents(key, ActionPass, 0)}, 2},
+		{"never passed", []Events{endedEvents(key, ActionFail, 0), endedEvents(key, ActionFail, 0)}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := Retries{key: tc.attempts}
+			if got := r.PassedOn(key); got != tc.want {
+				t.Errorf("got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRetryStatus(t *testing.T) {
+	flaky := Key{Package: "example.com/a", Test: "TestFlaky"}
+	broken := Key{Package: "example.com/a", Test: "TestBroken"}
+	tests := NewTestStorage(nil)
+	for _, key := range []Key{flaky, broken} {
+		for _, e := range endedEvents(key, ActionFail, 0.1, "    a_test.go:1: bad") {
+			tests.Append(e)
+		}
+	}
+	tests.Retries = Retries{
+		flaky:  {endedEvents(flaky, ActionPass, 0.2, "ok")},
+		broken: {endedEvents(broken, ActionFail, 0.3, "    a_test.go:1: bad")},
+	}
+	tests.ResolveStatuses()
+	if got := tests.StatusOf(flaky); got != StatusFlaky {
+		t.Errorf("%v: got %v, want %v", flaky, got, StatusFlaky)
+	}
+	if got, want := tests.Note(flaky), "passed on retry 1"; got != want {
+		t.Errorf("%v: note %q, want %q", flaky, got, want)
+	}
+	if got := tests.StatusOf(broken); got != StatusFail {
+		t.Errorf("%v: got %v, want %v", broken, got, StatusFail)
+	}
+	want := []ReportAttempt{{Status: StatusFail, Elapsed: 0.3, Output: "    a_test.go:1: bad"}}
+	if got := tests.Retries.ReportAttempts(broken); !reflect.DeepEqual(got, want) {
+		t.Errorf("attempts %+v, want %+v", got, want)
+	}
+}
+
+func TestRetryArgs(t *testing.T) {
+	for _, tc := range []struct {
+		argv []string
+		want []string
+	}{
+		{nil, []string{"-count=1"}},
+		{[]string{"-race", "-count=5", "./..."}, []string{"-race", "-count=1"}},
+		{[]string{"-count", "3", "-tags", "integration"}, []string{"-tags=integration", "-count=1"}},
+		{[]string{"--count=2"}, []string{"-count=1"}},
+	} {
+		if got := RetryArgs(tc.argv); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("RetryArgs(%q) = %q, want %q", tc.argv, got, tc.want)
+		}
+	}
+}
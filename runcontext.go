@@ -0,0 +1,46 @@
+package main
+
+// RunContext is what tests are classified and printed in besides their own
+// events. The zero RunContext classifies tests by their events alone.
+type RunContext struct {
+	// Modules are the module paths of the main module (or workspace),
+	// used to tell in-module stack frames from runtime, testing and
+	// dependency frames.
+	Modules []string
+
+	// Sources looks up the source of file:line references in test
+	// output, nil when there are no packages to look up.
+	Sources *SourceCache
+
+	// Links wraps test names and file locations in OSC 8 hyperlinks, nil
+	// when hyperlinks are disabled.
+	Links *Linker
+
+	// Blames looks up the last change of failing lines, nil when blame is
+	// disabled.
+	Blames *BlameCache
+
+	// CodeOwners maps packages to their owners, nil when there is no
+	// CODEOWNERS file or owners are disabled.
+	CodeOwners *CodeOwners
+
+	// Retries holds the retry attempts of failed tests, nil until failed
+	// tests are retried.
+	Retries Retries
+
+	// Quarantine holds the known flaky tests whose failures don't fail the
+	// run, nil when there is no quarantine file.
+	Quarantine *Quarantine
+
+	// XFails holds the tests that are expected to fail, nil when there is
+	// no xfail file.
+	XFails *XFails
+
+	// FailureHistory tags failures as new or known from the previous runs,
+	// nil when the history is disabled.
+	FailureHistory *FailureHistory
+
+	// Redactor replaces secrets in test output and in the arguments and
+	// flags written to reports and the history, nil in tests.
+	Redactor *Redactor
+}
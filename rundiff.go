@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Kinds of TestChange.
+const (
+	ChangeNewFailure  = "new failure"
+	ChangeFixed       = "fixed"
+	ChangeStatus      = "status"
+	ChangeAppeared    = "appeared"
+	ChangeDisappeared = "disappeared"
+	ChangeSlower      = "slower"
+	ChangeFaster      = "faster"
+)
+
+// minDurationChange is the duration change in seconds below which tgo diff
+// doesn't report tests as slower or faster, however large the relative change.
+const minDurationChange = 0.1
+
+// TestChange is the change of a key from one run to another. Before and After
+// are "" for keys missing from the run.
+type TestChange struct {
+	Kind          string
+	Package       string
+	Test          string `json:",omitempty"`
+	Before        Status `json:",omitempty"`
+	After         Status `json:",omitempty"`
+	BeforeElapsed float64
+	AfterElapsed  float64
+}
+
+// Key returns the key of c.
+func (c TestChange) Key() Key {
+	return Key{Package: c.Package, Test: c.Test}
+}
+
+// CoverageChange is the change of the coverage of a package from one run to
+// another.
+type CoverageChange struct {
+	Package string
+	Before  string `json:",omitempty"`
+	After   string `json:",omitempty"`
+}
+
+// Delta returns the change in percentage points, 0 unless both coverages are
+// known.
+func (c CoverageChange) Delta() float64 {
+	before, err1 := parseCoverage(c.Before)
+	after, err2 := parseCoverage(c.After)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return after - before
+}
+
+// parseCoverage parses a coverage like "71.3%".
+func parseCoverage(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+}
+
+// RunDiff is the difference between two runs, written by tgo diff.
+type RunDiff struct {
+	Before   string
+	After    string
+	Tests    []TestChange
+	Coverage []CoverageChange
+}
+
+// Count returns the number of test changes of kind.
+func (d RunDiff) Count(kind string) int {
+	n := 0
+	for _, c := range d.Tests {
+		if c.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// DiffRuns compares the run after to the run before. Tests are reported slower
+// or faster when their duration changed by more than the relative change
+// durationChange. Package keys are only compared for packages without tests
+// in either run, ie. packages that failed to build.
+func DiffRuns(before, after HistoryRun, durationChange float64) RunDiff {
+	var d RunDiff
+	a, b := before.Results(), after.Results()
+	withTests := make(map[string]bool)
+	for _, run := range []HistoryRun{before, after} {
+		for _, t := range run.Tests {
+			if t.Test != "" {
+				withTests[t.Package] = true
+			}
+		}
+	}
+
+	compare := func(key Key, ta, tb HistoryTest, inA, inB bool) {
+		if key.Test == "" && withTests[key.Package] {
+			return
+		}
+		c := TestChange{
+			Package:       key.Package,
+			Test:          key.Test,
+			Before:        ta.Status,
+			After:         tb.Status,
+			BeforeElapsed: ta.Elapsed,
+			AfterElapsed:  tb.Elapsed,
+		}
+		failedA, failedB := LastFailedStatuses.Any(ta.Status), LastFailedStatuses.Any(tb.Status)
+		switch {
+		case inB && failedB && !failedA:
+			c.Kind = ChangeNewFailure
+		case !inB:
+			c.Kind = ChangeDisappeared
+		case !inA:
+			c.Kind = ChangeAppeared
+		case failedA && tb.Status == StatusPass:
+			c.Kind = ChangeFixed
+		case ta.Status != tb.Status:
+			c.Kind = ChangeStatus
+		case ta.Status == StatusPass && math.Abs(tb.Elapsed-ta.Elapsed) >= minDurationChange &&
+			math.Abs(tb.Elapsed-ta.Elapsed) > durationChange*ta.Elapsed:
+			c.Kind = ChangeFaster
+			if tb.Elapsed > ta.Elapsed {
+				c.Kind = ChangeSlower
+			}
+		default:
+			return
+		}
+		d.Tests = append(d.Tests, c)
+	}
+
+	for _, t := range before.Tests {
+		tb, ok := b[t.Key()]
+		compare(t.Key(), t, tb, true, ok)
+	}
+	for _, t := range after.Tests {
+		if _, ok := a[t.Key()]; !ok {
+			compare(t.Key(), HistoryTest{}, t, false, true)
+		}
+	}
+
+	for _, t := range after.Tests {
+		if t.Test != "" {
+			continue
+		}
+		if ta := a[t.Key()]; ta.Coverage != t.Coverage {
+			d.Coverage = append(d.Coverage, CoverageChange{Package: t.Package, Before: ta.Coverage, After: t.Coverage})
+		}
+	}
+	for _, t := range before.Tests {
+		if _, ok := b[t.Key()]; !ok && t.Test == "" && t.Coverage != "" {
+			d.Coverage = append(d.Coverage, CoverageChange{Package: t.Package, Before: t.Coverage})
+		}
+	}
+	return d
+}
+
+// ReadRun reads the go test -json output saved in filename as a HistoryRun.
+func ReadRun(flags Flags, filename string) (HistoryRun, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return HistoryRun{}, err
+	}
+	defer f.Close()
+	redactor, err := NewRedactor(flags.Redact, flags.RedactRegexp, flags.RedactEnv)
+	if err != nil {
+		return HistoryRun{}, err
+	}
+	tests := readEvents(f, &RunContext{Redactor: redactor})
+	if len(tests.Tests) == 0 {
+		return HistoryRun{}, fmt.Errorf("%s: no go test -json events", filename)
+	}
+	return tests.HistoryRun(flags, nil), nil
+}
+
+// Diff implements tgo diff, comparing the go test -json output saved in the
+// files before and after. It fails when tests fail in after that didn't in
+// before.
+func Diff(ctx context.Context, flags Flags, before, after string) error {
+	runA, err := ReadRun(flags, before)
+	if err != nil {
+		return err
+	}
+	runB, err := ReadRun(flags, after)
+	if err != nil {
+		return err
+	}
+	d := DiffRuns(runA, runB, flags.DiffDuration)
+	d.Before, d.After = before, after
+
+	sources := NewSourceCache(ctx, flags.Bin)
+	links, err := NewLinker(flags.Links, flags.LinkTemplate, sources)
+	if err != nil {
+		return err
+	}
+	if links != nil {
+		var pkgs []string
+		for _, c := range d.Tests {
+			pkgs = append(pkgs, c.Package)
+		}
+		for _, c := range d.Coverage {
+			pkgs = append(pkgs, c.Package)
+		}
+		sources.Load(pkgs)
+	}
+	if flags.DiffJSON {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		d.Print(links)
+	}
+	if d.Count(ChangeNewFailure) > 0 {
+		return ExitError(1)
+	}
+	return nil
+}
+
+// Print prints the changes in d grouped by kind.
+func (d RunDiff) Print(links *Linker) {
+	sections := []struct {
+		kind  string
+		title string
+		color func(a ...interface{}) string
+		bold  func(a ...interface{}) string
+	}{
+		{ChangeNewFailure, "NEW FAILURES", failColor, failColorBold},
+		{ChangeFixed, "FIXED", passColor, passColorBold},
+		{ChangeStatus, "STATUS CHANGES", noneColor, noneColorBold},
+		{ChangeAppeared, "APPEARED", timeColor, timeColor},
+		{ChangeDisappeared, "DISAPPEARED", skipColor, skipColorBold},
+		{ChangeSlower, "SLOWER", timeoutColor, timeoutColorBold},
+		{ChangeFaster, "FASTER", passColor, passColorBold},
+	}
+	for _, s := range sections {
+		if d.Count(s.kind) == 0 {
+			continue
+		}
+		hr := s.color("════════════")
+		fmt.Println(hr, s.bold(s.title), hr)
+		for _, c := range d.Tests {
+			if c.Kind != s.kind {
+				continue
+			}
+			key := c.Key()
+			text := links.LinkPackage(key.Package, packageColor(key.Package))
+			if key.Test != "" {
+				text += "." + links.LinkTest(key, testColor(key.Test))
+			}
+			var change string
+			switch c.Kind {
+			case ChangeSlower, ChangeFaster:
+				change = timeColor(fmt.Sprintf("%.2fs → %.2fs", c.BeforeElapsed, c.AfterElapsed))
+				if c.BeforeElapsed > 0 {
+					change += " " + s.color(fmt.Sprintf("(%+.0f%%)", (c.AfterElapsed/c.BeforeElapsed-1)*100))
+				}
+			default:
+				change = statusName(c.Before) + " → " + statusName(c.After)
+			}
+			fmt.Println("  " + text + "  " + change)
+		}
+	}
+
+	if len(d.Coverage) > 0 {
+		hr := coverColor("════════════")
+		fmt.Println(hr, coverColor("COVR"), hr)
+		for _, c := range d.Coverage {
+			change := fmt.Sprintf("%s → %s", orDash(c.Before), orDash(c.After))
+			if delta := c.colorDelta(); delta != "" {
+				change += " " + delta
+			}
+			fmt.Println("  " + links.LinkPackage(c.Package, packageColor(c.Package)) + "  " + change)
+		}
+	}
+
+	fmt.Println("")
+	var counts []string
+	for _, s := range sections {
+		if n := d.Count(s.kind); n > 0 {
+			counts = append(counts, s.bold(fmt.Sprintf("%s:%d", strings.ToUpper(s.kind), n)))
+		}
+	}
+	if len(counts) == 0 {
+		counts = append(counts, passColorBold("no changes"))
+	}
+	fmt.Println("══════ " + strings.Join(counts, " | ") + " ══════")
+}
+
+// colorDelta returns the colored change of c in percentage points, "" unless
+// both coverages are known.
+func (c CoverageChange) colorDelta() string {
+	delta := c.Delta()
+	switch {
+	case c.Before == "" || c.After == "":
+		return ""
+	case delta < 0:
+		return failColor(fmt.Sprintf("(%+.1f)", delta))
+	default:
+		return passColor(fmt.Sprintf("(%+.1f)", delta))
+	}
+}
+
+// statusName returns the colored name of status, "-" for "".
+func statusName(status Status) string {
+	if status == "" {
+		return "-"
+	}
+	return statusColors[status](statusNames[status])
+}
+
+// orDash returns s or "-" if s is "".
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
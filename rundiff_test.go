@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCoverage(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"71.3%", 71.3, true},
+		{"100.0%", 100, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"[no statements]", 0, false},
+	} {
+		got, err := parseCoverage(tc.s)
+		if got != tc.want || (err == nil) != tc.ok {
+			t.Errorf("parseCoverage(%q) = %v, %v, want %v, ok %v", tc.s, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCoverageDelta(t *testing.T) {
+	for _, tc := range []struct {
+		before, after string
+		want          float64
+	}{
+		{"50.0%", "75.5%", 25.5},
+		{"75.5%", "50.0%", -25.5},
+		{"", "75.5%", 0},
+		{"50.0%", "", 0},
+	} {
+		c := CoverageChange{Before: tc.before, After: tc.after}
+		if got := c.Delta(); got != tc.want {
+			t.Errorf("%q → %q: got %v, want %v", tc.before, tc.after, got, tc.want)
+		}
+	}
+}
+
+func TestDiffRuns(t *testing.T) {
+	pkg := "example.com/a"
+	test := func(name string, status Status, elapsed float64) HistoryTest {
+		return HistoryTest{Package: pkg, Test: name, Status: status, Elapsed: elapsed}
+	}
+	before := HistoryRun{Tests: []HistoryTest{
+		test("TestBreaks", StatusPass, 0.1),
+		test("TestFixed", StatusFail, 0.1),
+		test("TestSkipped", StatusPass, 0.1),
+		test("TestGone", StatusPass, 0.1),
+		test("TestSlow", StatusPass, 1),
+		test("TestFast", StatusPass, 1),
+		test("TestTiny", StatusPass, 0.01),
+		test("TestSame", StatusPass, 1),
+		{Package: pkg, Status: StatusFail, Coverage: "50.0%"},
+		{Package: "example.com/build", Status: StatusPass, Coverage: "10.0%"},
+	}}
+	after := HistoryRun{Tests: []HistoryTest{
+		test("TestBreaks", StatusFail, 0.1),
+		test("TestFixed", StatusPass, 0.1),
+		test("TestSkipped", StatusSkip, 0),
+		test("TestSlow", StatusPass, 2),
+		test("TestFast", StatusPass, 0.5),
+		test("TestTiny", StatusPass, 0.05),
+		test("TestSame", StatusPass, 1.05),
+		test("TestNew", StatusPass, 0.1),
+		{Package: pkg, Status: StatusFail, Coverage: "55.0%"},
+		{Package: "example.com/build", Status: StatusFail},
+	}}
+	d := DiffRuns(before, after, 0.2)
+	want := []TestChange{
+		{Kind: ChangeNewFailure, Package: pkg, Test: "TestBreaks", Before: StatusPass, After: StatusFail, BeforeElapsed: 0.1, AfterElapsed: 0.1},
+		{Kind: ChangeFixed, Package: pkg, Test: "TestFixed", Before: StatusFail, After: StatusPass, BeforeElapsed: 0.1, AfterElapsed: 0.1},
+		{Kind: ChangeStatus, Package: pkg, Test: "TestSkipped", Before: StatusPass, After: StatusSkip, BeforeElapsed: 0.1},
+		{Kind: ChangeDisappeared, Package: pkg, Test: "TestGone", Before: StatusPass, BeforeElapsed: 0.1},
+		{Kind: ChangeSlower, Package: pkg, Test: "TestSlow", Before: StatusPass, After: StatusPass, BeforeElapsed: 1, AfterElapsed: 2},
+		{Kind: ChangeFaster, Package: pkg, Test: "TestFast", Before: StatusPass, After: StatusPass, BeforeElapsed: 1, AfterElapsed: 0.5},
+		{Kind: ChangeNewFailure, Package: "example.com/build", Before: StatusPass, After: StatusFail},
+		{Kind: ChangeAppeared, Package: pkg, Test: "TestNew", After: StatusPass, AfterElapsed: 0.1},
+	}
+	if !reflect.DeepEqual(d.Tests, want) {
+		t.Errorf("tests\ngot  %+v\nwant %+v", d.Tests, want)
+	}
+	wantCoverage := []CoverageChange{
+		{Package: pkg, Before: "50.0%", After: "55.0%"},
+		{Package: "example.com/build", Before: "10.0%"},
+	}
+	if !reflect.DeepEqual(d.Coverage, wantCoverage) {
+		t.Errorf("coverage\ngot  %+v\nwant %+v", d.Coverage, wantCoverage)
+	}
+	if got := d.Count(ChangeNewFailure); got != 2 {
+		t.Errorf("%d new failures, want 2", got)
+	}
+}
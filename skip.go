@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SkipReason returns the message a skipped test was skipped with, the last
+// message it logged, without the file:line prefix. It is "" if the test
+// logged nothing, e.g. when it called t.SkipNow.
+func (es Events) SkipReason() string {
+	var reason string
+	for _, e := range es.Compact() {
+		if e.Action != ActionOutput {
+			continue
+		}
+		if _, ok := ParseFileRef(e.Output); ok {
+			reason = strings.TrimSpace(fileRefRe.ReplaceAllString(e.Output, ""))
+		}
+	}
+	return reason
+}
+
+// SkipReasons returns the skip reasons of the skipped tests in ts.
+func (ts TestStorage) SkipReasons() map[Key]string {
+	reasons := make(map[Key]string)
+	for key, events := range ts.Tests {
+		if key.Test == "" || events.Status() != StatusSkip {
+			continue
+		}
+		if reason := events.SkipReason(); reason != "" {
+			reasons[key] = reason
+		}
+	}
+	return reasons
+}
+
+// SkipGroup is a set of tests skipped with the same normalised reason.
+type SkipGroup struct {
+	Reason string // the reason of the first test in the group
+	Keys   []Key
+}
+
+// SkipGroups groups the skipped tests in ts by their normalised skip reason,
+// largest groups first. Tests skipped without a reason are grouped under "".
+func (ts TestStorage) SkipGroups() []*SkipGroup {
+	var groups []*SkipGroup
+	byFingerprint := make(map[string]*SkipGroup)
+	for _, key := range ts.OrderedKeys() {
+		if key.Test == "" || ts.Tests[key].Status() != StatusSkip {
+			continue
+		}
+		reason := ts.Tests[key].SkipReason()
+		fp := FailureFingerprint(reason)
+		g, ok := byFingerprint[fp]
+		if !ok {
+			g = &SkipGroup{Reason: reason}
+			byFingerprint[fp] = g
+			groups = append(groups, g)
+		}
+		g.Keys = append(g.Keys, key)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Keys) > len(groups[j].Keys)
+	})
+	return groups
+}
+
+// PrintSkipGroups prints the number of tests in ts skipped for each reason.
+func (ts TestStorage) PrintSkipGroups(flags Flags) {
+	groups := ts.SkipGroups()
+	if len(groups) == 0 {
+		return
+	}
+	hr := skipColor("════════════")
+	fmt.Println(hr, skipColorBold("SKIP REASONS"), hr)
+	for _, g := range groups {
+		reason := TruncateMiddle(g.Reason, flags.truncateWidth())
+		if reason == "" {
+			reason = timeColor("(no reason)")
+		}
+		tests := "tests"
+		if len(g.Keys) == 1 {
+			tests = "test"
+		}
+		fmt.Printf("  %s: %s\n", reason, skipColorBold(fmt.Sprintf("%d %s", len(g.Keys), tests)))
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSkipReasons(t *testing.T) {
+	tests := loadTests(t, "skip.json")
+	pkg := "example.com/ex/skip"
+	want := map[Key]string{
+		{Package: pkg, Test: "TestNeedsDB1"}: "no database on port 5432",
+		{Package: pkg, Test: "TestNeedsDB2"}: "no database on port 5433",
+		{Package: pkg, Test: "TestLogged"}:   "flaky on linux",
+	}
+	if got := tests.SkipReasons(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSkipGroups(t *testing.T) {
+	tests := loadTests(t, "skip.json")
+	var got [][]string
+	for _, g := range tests.SkipGroups() {
+		group := []string{g.Reason}
+		for _, key := range g.Keys {
+			group = append(group, key.Test)
+		}
+		got = append(got, group)
+	}
+	want := [][]string{
+		{"no database on port 5432", "TestNeedsDB1", "TestNeedsDB2"},
+		{"flaky on linux", "TestLogged"},
+		{"", "TestNow"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SourceCache caches package directories and source files. Package
+// directories are looked up with go list on first use, which is safe to do
+// once go test is done.
+type SourceCache struct {
+	ctx      context.Context
+	bin      string
+	packages Packages
+	files    map[string][]string
+	funcs    map[string]map[string]FileRef
+}
+
+func NewSourceCache(ctx context.Context, bin string) *SourceCache {
+	return &SourceCache{
+		ctx:      ctx,
+		bin:      bin,
+		packages: make(Packages),
+		files:    make(map[string][]string),
+		funcs:    make(map[string]map[string]FileRef),
+	}
+}
+
+// Load looks up the directories of the packages pkgs that aren't known yet
+// with a single go list.
+func (c *SourceCache) Load(pkgs []string) {
+	var missing []string
+	for _, pkg := range pkgs {
+		if _, ok := c.packages[pkg]; !ok {
+			missing = append(missing, pkg)
+		}
+	}
+	packages, _ := ListPackages(c.ctx, c.bin, missing)
+	for _, pkg := range missing {
+		// remember failures too so go list only runs once per package
+		c.packages[pkg] = packages[pkg]
+	}
+}
+
+// Dir returns the directory of the package pkg, "" if it is unknown.
+func (c *SourceCache) Dir(pkg string) string {
+	p, ok := c.packages[pkg]
+	if !ok {
+		packages, _ := ListPackages(c.ctx, c.bin, []string{pkg})
+		p = packages[pkg]
+		// remember failures too so go list only runs once per package
+		c.packages[pkg] = p
+	}
+	return p.Dir
+}
+
+// Loaded returns the directory of the package pkg if it was already looked
+// up, without running go list.
+func (c *SourceCache) Loaded(pkg string) (string, bool) {
+	p, ok := c.packages[pkg]
+	return p.Dir, ok && p.Dir != ""
+}
+
+// Lines returns the lines of filename, nil if it can't be read.
+func (c *SourceCache) Lines(filename string) []string {
+	lines, ok := c.files[filename]
+	if !ok {
+		data, err := os.ReadFile(filename)
+		if err == nil {
+			lines = strings.Split(string(data), "\n")
+		}
+		c.files[filename] = lines
+	}
+	return lines
+}
+
+// Resolve returns the path of file as referenced from the output of a test in
+// pkg.
+func (c *SourceCache) Resolve(pkg, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	dir := c.Dir(pkg)
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, file)
+}
+
+// FileRef is a file:line reference in test output.
+type FileRef struct {
+	File string
+	Line int
+}
+
+var fileRefRe = regexp.MustCompile(`^\s*([^\s:]+\.go):(\d+): `)
+
+// ParseFileRef parses the file:line prefix go test adds to t.Log and t.Error
+// output.
+func ParseFileRef(output string) (FileRef, bool) {
+	m := fileRefRe.FindStringSubmatch(output)
+	if m == nil {
+		return FileRef{}, false
+	}
+	line, _ := strconv.Atoi(m[2])
+	return FileRef{File: m[1], Line: line}, true
+}
+
+// snippetContext returns the number of source lines shown around a
+// referenced line.
+func (f Flags) snippetContext() int {
+	switch {
+	case f.V >= V3:
+		return 3
+	case f.V >= V2:
+		return 2
+	case f.V >= V1:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Snippet returns the source lines around ref with the referenced line
+// highlighted, each prefixed with indent.
+func (c *SourceCache) Snippet(pkg string, ref FileRef, around int, indent string) []string {
+	filename := c.Resolve(pkg, ref.File)
+	if filename == "" {
+		return nil
+	}
+	lines := c.Lines(filename)
+	if ref.Line < 1 || ref.Line > len(lines) {
+		return nil
+	}
+	from := max(ref.Line-around, 1)
+	to := min(ref.Line+around, len(lines))
+	width := len(strconv.Itoa(to))
+
+	// strip the indentation common to all lines of the snippet
+	texts := make([]string, 0, to-from+1)
+	dedent := -1
+	for n := from; n <= to; n++ {
+		text := strings.ReplaceAll(lines[n-1], "\t", "    ")
+		if strings.TrimSpace(text) != "" {
+			if d := len(leadingSpace(text)); dedent < 0 || d < dedent {
+				dedent = d
+			}
+		}
+		texts = append(texts, text)
+	}
+
+	var snippet []string
+	for n := from; n <= to; n++ {
+		text := texts[n-from]
+		if len(text) >= dedent && dedent > 0 {
+			text = text[dedent:]
+		}
+		gutter := fmt.Sprintf("%*d |", width, n)
+		if n == ref.Line {
+			snippet = append(snippet, indent+failColorBold("> "+gutter+" "+text))
+		} else {
+			snippet = append(snippet, indent+"  "+coverColor(gutter)+" "+text)
+		}
+	}
+	return snippet
+}
+
+// AddSnippets inserts source snippets for the file:line references in lines,
+// after the referencing message and its continuation lines. Consecutive
+// references to the same line get a single snippet.
+func (c *SourceCache) AddSnippets(flags Flags, lines []OutputLine) []OutputLine {
+	var (
+		result  []OutputLine
+		pending []OutputLine
+		prefix  string  // indentation of the continuation lines of a message
+		last    FileRef // the reference of the last snippet
+	)
+	for _, line := range lines {
+		output := line.Event.Output
+		if len(pending) > 0 && (output == pending[0].Event.Output || strings.HasPrefix(output, prefix)) {
+			result = append(result, line)
+			continue
+		}
+		result = append(result, pending...)
+		pending = nil
+		result = append(result, line)
+		// a log writer helper references the same line for every message
+		if ref, ok := ParseFileRef(output); ok && ref != last {
+			last = ref
+			indent := leadingSpace(output) + "    "
+			prefix = indent
+			for _, text := range c.Snippet(line.Event.Package, ref, flags.snippetContext(), indent) {
+				pending = append(pending, OutputLine{Event: line.Event, Text: text})
+			}
+		}
+	}
+	return append(result, pending...)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFileRef(t *testing.T) {
+	for _, tc := range []struct {
+		output string
+		want   FileRef
+		ok     bool
+	}{
+		{"    foo_test.go:123: got 1\n", FileRef{File: "foo_test.go", Line: 123}, true},
+		{"        sub_test.go:7: nested\n", FileRef{File: "sub_test.go", Line: 7}, true},
+		{"foo_test.go:1: \n", FileRef{File: "foo_test.go", Line: 1}, true},
+		{"    see foo_test.go:123: here\n", FileRef{}, false},
+		{"    foo.txt:1: not go\n", FileRef{}, false},
+		{"=== RUN   TestX\n", FileRef{}, false},
+	} {
+		got, ok := ParseFileRef(tc.output)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ParseFileRef(%q) = %v, %v, want %v, %v", tc.output, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// writeSource writes a test file to a temporary directory and returns its
+// path.
+func writeSource(t *testing.T) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "x_test.go")
+	src := "package x\n\nfunc TestX(t *testing.T) {\n\tif true {\n\t\tt.Error(\"bad\")\n\t}\n}\n"
+	if err := os.WriteFile(filename, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestSnippet(t *testing.T) {
+	filename := writeSource(t)
+	c := NewSourceCache(context.Background(), "go")
+	for _, tc := range []struct {
+		name   string
+		line   int
+		around int
+		want   []string
+	}{
+		{"line only", 5, 0, []string{"  > 5 | t.Error(\"bad\")"}},
+		{
+			"dedented context", 5, 1, []string{
+				"    4 | if true {",
+				"  > 5 |     t.Error(\"bad\")",
+				"    6 | }",
+			},
+		},
+		{
+			"first line", 1, 1, []string{
+				"  > 1 | package x",
+				"    2 | ",
+			},
+		},
+		{"out of range", 100, 1, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := c.Snippet("example.com/x", FileRef{File: filename, Line: tc.line}, tc.around, "  ")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddSnippets(t *testing.T) {
+	filename := writeSource(t)
+	c := NewSourceCache(context.Background(), "go")
+	key := Key{Package: "example.com/x", Test: "TestX"}
+	es := outputEvents(key,
+		"    "+filename+":5: bad",
+		"        continued",
+		"    "+filename+":5: bad again",
+		"--- FAIL: TestX (0.00s)",
+	)
+	var lines []OutputLine
+	for _, e := range es {
+		lines = append(lines, OutputLine{Event: e, Text: e.Output[:len(e.Output)-1]})
+	}
+	want := []string{
+		"    " + filename + ":5: bad",
+		"        continued",
+		"        > 5 | t.Error(\"bad\")",
+		"    " + filename + ":5: bad again",
+		"--- FAIL: TestX (0.00s)",
+	}
+	got := renderedText(c.AddSnippets(Flags{}, lines))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
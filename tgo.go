@@ -54,11 +54,18 @@ var (
 )
 
 var (
-	StatusPass  = Status(ActionPass)
-	StatusFail  = Status(ActionFail)
-	StatusSkip  = Status(ActionSkip)
-	StatusBench = Status(ActionBench)
-	StatusNone  = Status("none")
+	StatusPass        = Status(ActionPass)
+	StatusFail        = Status(ActionFail)
+	StatusSkip        = Status(ActionSkip)
+	StatusBench       = Status(ActionBench)
+	StatusNone        = Status("none")
+	StatusPanic       = Status("panic")
+	StatusTimeout     = Status("timeout")
+	StatusRace        = Status("race")
+	StatusFlaky       = Status("flaky")
+	StatusQuarantined = Status("quarantined")
+	StatusXFail       = Status("xfail")
+	StatusXPass       = Status("xpass")
 
 	AllStatuses = Statuses{
 		StatusBench,
@@ -66,18 +73,35 @@ var (
 		StatusSkip,
 		StatusNone,
 		StatusFail,
+		StatusPanic,
+		StatusTimeout,
+		StatusRace,
+		StatusFlaky,
+		StatusQuarantined,
+		StatusXFail,
+		StatusXPass,
 	}
 	DefaultStatuses = Statuses{
 		StatusNone,
 		StatusFail,
 	}
 
+	// FailureStatuses are the ending statuses of failed tests.
+	FailureStatuses = Statuses{StatusFail, StatusPanic, StatusRace}
+
 	statusNames = map[Status]string{
-		StatusFail:  "FAIL",
-		StatusPass:  "PASS",
-		StatusNone:  "NONE",
-		StatusSkip:  "SKIP",
-		StatusBench: "BENCH",
+		StatusFail:        "FAIL",
+		StatusPass:        "PASS",
+		StatusNone:        "NONE",
+		StatusSkip:        "SKIP",
+		StatusBench:       "BENCH",
+		StatusPanic:       "PANIC",
+		StatusTimeout:     "TIMEOUT",
+		StatusRace:        "RACE",
+		StatusFlaky:       "FLAKY",
+		StatusQuarantined: "QUARANTINED",
+		StatusXFail:       "XFAIL",
+		StatusXPass:       "XPASS",
 	}
 )
 
@@ -103,20 +127,55 @@ var (
 	skipColor     = color.New(color.FgHiMagenta).SprintFunc()
 	skipColorBold = color.New(color.FgHiMagenta, color.Bold).SprintFunc()
 
+	panicColor     = color.New(color.FgHiRed).SprintFunc()
+	panicColorBold = color.New(color.FgHiRed, color.Bold).SprintFunc()
+
+	timeoutColor     = color.New(color.FgHiYellow).SprintFunc()
+	timeoutColorBold = color.New(color.FgHiYellow, color.Bold).SprintFunc()
+
+	raceColor     = color.New(color.FgHiBlue).SprintFunc()
+	raceColorBold = color.New(color.FgHiBlue, color.Bold).SprintFunc()
+
+	flakyColor     = color.New(color.FgHiCyan).SprintFunc()
+	flakyColorBold = color.New(color.FgHiCyan, color.Bold).SprintFunc()
+
+	quarantineColor     = color.New(color.FgHiBlack).SprintFunc()
+	quarantineColorBold = color.New(color.FgHiBlack, color.Bold).SprintFunc()
+
+	xfailColor     = color.New(color.FgHiGreen).SprintFunc()
+	xfailColorBold = color.New(color.FgHiGreen, color.Bold).SprintFunc()
+
+	xpassColor     = color.New(color.FgHiWhite, color.BgRed).SprintFunc()
+	xpassColorBold = color.New(color.FgHiWhite, color.BgRed, color.Bold).SprintFunc()
+
 	statusColors = map[Status](func(a ...interface{}) string){
-		StatusFail:  failColor,
-		StatusPass:  passColor,
-		StatusNone:  noneColor,
-		StatusSkip:  skipColor,
-		StatusBench: passColor,
+		StatusFail:        failColor,
+		StatusPass:        passColor,
+		StatusNone:        noneColor,
+		StatusSkip:        skipColor,
+		StatusBench:       passColor,
+		StatusPanic:       panicColor,
+		StatusTimeout:     timeoutColor,
+		StatusRace:        raceColor,
+		StatusFlaky:       flakyColor,
+		StatusQuarantined: quarantineColor,
+		StatusXFail:       xfailColor,
+		StatusXPass:       xpassColor,
 	}
 
 	statusColorsBold = map[Status](func(a ...interface{}) string){
-		StatusFail:  failColorBold,
-		StatusPass:  passColorBold,
-		StatusNone:  noneColorBold,
-		StatusSkip:  skipColorBold,
-		StatusBench: passColorBold,
+		StatusFail:        failColorBold,
+		StatusPass:        passColorBold,
+		StatusNone:        noneColorBold,
+		StatusSkip:        skipColorBold,
+		StatusBench:       passColorBold,
+		StatusPanic:       panicColorBold,
+		StatusTimeout:     timeoutColorBold,
+		StatusRace:        raceColorBold,
+		StatusFlaky:       flakyColorBold,
+		StatusQuarantined: quarantineColorBold,
+		StatusXFail:       xfailColorBold,
+		StatusXPass:       xpassColorBold,
 	}
 )
 
@@ -130,11 +189,42 @@ type Flags struct {
 	Bin              string
 	All              bool
 	PrintConfig      bool
+	Tree             bool
+	FailParents      bool
+	UpdateExamples   bool
+	Source           bool
+	Links            string
+	LinkTemplate     string
+	Groups           bool
+	Rerun            bool
+	Report           string
+	LogLevel         string
+	OutputHead       int
+	OutputTail       int
+	Artifacts        string
+	Redact           bool
+	RedactRegexp     string
+	RedactEnv        string
+	Blame            bool
+	Owners           bool
+	CodeOwners       string
+	History          bool
+	HistoryKeep      int
+	LastFailed       bool
+	Retries          int
+	FailFlaky        bool
+	Quarantine       string
+	QuarantineRuns   int
+	XFail            string
+	DiffJSON         bool
+	DiffDuration     float64
+	StillFailingRuns int
+	FlakyRuns        int
 }
 
 func (f *Flags) Register(fs *flag.FlagSet) {
-	f.Results = Statuses{StatusFail, StatusNone}
-	f.Summary = Statuses{StatusFail, StatusNone}
+	f.Results = Statuses{StatusFail, StatusPanic, StatusTimeout, StatusRace, StatusNone}
+	f.Summary = Statuses{StatusFail, StatusPanic, StatusTimeout, StatusRace, StatusFlaky, StatusQuarantined, StatusXFail, StatusXPass, StatusNone}
 
 	fs.StringVar(&f.Bin, "bin", "go", "go binary name")
 	fs.Var(&f.Results, "results", "types of results to show")
@@ -144,6 +234,37 @@ func (f *Flags) Register(fs *flag.FlagSet) {
 	fs.StringVar(&f.Config, "config", "", "config file")
 	fs.BoolVar(&f.All, "all", false, "show mostly everything")
 	fs.BoolVar(&f.PrintConfig, "print_config", false, "print config")
+	fs.BoolVar(&f.Tree, "tree", false, "group subtests under their parent test")
+	fs.BoolVar(&f.FailParents, "fail-parents", false, "show tests that failed only because a subtest failed")
+	fs.BoolVar(&f.UpdateExamples, "update-examples", false, "update the output comments of failing examples")
+	fs.BoolVar(&f.Source, "source", false, "show source lines around file:line references in failures")
+	fs.StringVar(&f.Links, "links", "auto", "hyperlink tests and file locations: auto, always or never")
+	fs.StringVar(&f.LinkTemplate, "link-template", DefaultLinkTemplate, "hyperlink URL template")
+	fs.BoolVar(&f.Groups, "groups", true, "group failures with similar messages")
+	fs.BoolVar(&f.Rerun, "rerun", true, "print commands that rerun failed tests")
+	fs.StringVar(&f.Report, "report", "", "write a JSON report to this file")
+	fs.StringVar(&f.LogLevel, "log-level", "", "hide structured log lines below this level")
+	fs.IntVar(&f.OutputHead, "output-head", 100, "number of output lines shown from the start of a test")
+	fs.IntVar(&f.OutputTail, "output-tail", 100, "number of output lines shown from the end of a test")
+	fs.StringVar(&f.Artifacts, "artifacts", "", "directory the full output of truncated tests is written to")
+	fs.BoolVar(&f.Redact, "redact", true, "redact tokens, passwords and keys in test output")
+	fs.StringVar(&f.RedactRegexp, "redact-regexp", "", "also redact matches of this regular expression")
+	fs.StringVar(&f.RedactEnv, "redact-env", "", "also redact the values of these comma separated environment variables")
+	fs.BoolVar(&f.Blame, "blame", false, "show the last commit that changed failing lines")
+	fs.BoolVar(&f.Owners, "owners", true, "group failures by the owners from CODEOWNERS")
+	fs.StringVar(&f.CodeOwners, "codeowners", "", "CODEOWNERS file, found in the repository by default")
+	fs.BoolVar(&f.History, "history", true, "record the results of each run")
+	fs.IntVar(&f.HistoryKeep, "history-keep", 100, "number of runs kept in the history, 0 keeps all")
+	fs.BoolVar(&f.LastFailed, "last-failed", false, "run only the tests that failed in the last run")
+	fs.IntVar(&f.Retries, "retries", 0, "retry failed tests up to this many times")
+	fs.BoolVar(&f.FailFlaky, "fail-flaky", false, "fail the run when tests only passed on a retry")
+	fs.StringVar(&f.Quarantine, "quarantine", "", "quarantine file, .tgo-quarantine in the repository by default")
+	fs.IntVar(&f.QuarantineRuns, "quarantine-runs", 10, "runs a quarantined test must pass to be ready for removal")
+	fs.StringVar(&f.XFail, "xfail", "", "file of tests expected to fail, .tgo-xfail in the repository by default")
+	fs.BoolVar(&f.DiffJSON, "diff-json", false, "print tgo diff as JSON")
+	fs.Float64Var(&f.DiffDuration, "diff-duration", 0.5, "relative duration change tgo diff reports")
+	fs.IntVar(&f.StillFailingRuns, "still-failing-runs", 1, "previous runs in a row a test must have failed in to be still failing")
+	fs.IntVar(&f.FlakyRuns, "flaky-runs", 20, "previous runs looked at to find tests with an intermittent history")
 }
 
 func (f *Flags) PrintHelp(w io.Writer) {
@@ -160,6 +281,67 @@ tgo settings:
   TGO_RES_HIDE      types of results to hide when empty
   TGO_BIN=go        go binary name
   TGO_PRINT_CONFIG  print config on run
+  TGO_TREE=1        group subtests under their parent test
+  TGO_FAIL_PARENTS=1  show and count tests that failed only because a
+                    subtest failed
+  TGO_UPDATE_EXAMPLES=1  rewrite the // Output: comments of failing
+                    examples with their actual output
+  TGO_SOURCE=1      show source lines around file:line references in
+                    failures, more context with higher verbosity
+  TGO_LINKS=auto    hyperlink tests and file:line references: auto (when
+                    colors are enabled), always or never
+  TGO_LINK_TEMPLATE=file://{host}{path}#L{line}
+                    hyperlink URL, ie. vscode://file/{path}:{line}
+  TGO_GROUPS=1      group failing tests with similar failure messages
+  TGO_RERUN=1       print commands that rerun each failed test
+  TGO_REPORT        write a JSON report of the run to this file
+  TGO_LOG_LEVEL     hide JSON and logfmt log lines in test output below
+                    this level: debug, info, warn or error
+  TGO_OUTPUT_HEAD=100  TGO_OUTPUT_TAIL=100
+                    show the first and last lines of the output of a
+                    test and the failures in between, 0 for both shows
+                    all output
+  TGO_ARTIFACTS     directory the full output of truncated tests is
+                    written to, defaults to $XDG_CACHE_HOME/tgo/artifacts
+  TGO_REDACT=1      replace bearer tokens, passwords in URLs, AWS keys
+                    and PEM blocks in test output with [REDACTED]
+  TGO_REDACT_REGEXP also redact matches of this regular expression
+  TGO_REDACT_ENV    also redact the values of these comma separated
+                    environment variables, ie. DB_PASSWORD,API_TOKEN
+  TGO_BLAME=1       show the commit, author and date of the last change
+                    of failing lines, from git blame
+  TGO_OWNERS=1      group failures by the owners of their packages from
+                    the repository's CODEOWNERS file
+  TGO_CODEOWNERS    CODEOWNERS file to use instead of the repository's
+  TGO_HISTORY=1     record the results of each run in
+                    $XDG_CACHE_HOME/tgo/<module>
+  TGO_HISTORY_KEEP=100  number of runs kept in the history, 0 keeps all
+  TGO_LAST_FAILED=1 run only the tests that failed or didn't finish in
+                    the last run, same as tgo last-failed [go test flags]
+  TGO_RETRIES=0     retry failed tests up to this many times, tests that
+                    pass on a retry are FLAKY and don't fail the run
+  TGO_FAIL_FLAKY=1  fail the run when there are FLAKY tests
+  TGO_QUARANTINE    file of known flaky tests whose failures don't fail
+                    the run, .tgo-quarantine in the repository by default.
+                    Each line has a key pattern, owner, ticket and expiry
+                    date: example.com/pkg.TestFoo @team BUG-1 2025-12-31
+  TGO_QUARANTINE_RUNS=10  runs a quarantined test must pass in a row to
+                    be reported as ready to be removed
+  TGO_XFAIL         file of tests expected to fail, .tgo-xfail in the
+                    repository by default. Each line has a key pattern
+                    and optionally a regexp the failure message must
+                    match: example.com/pkg.TestBug want 3, got 2
+                    Expected failures are XFAIL and don't fail the run,
+                    tests that pass are XPASS and fail it
+  TGO_DIFF_JSON=1   print the comparison of two saved go test -json
+                    outputs by tgo diff <run A> <run B> as JSON
+  TGO_DIFF_DURATION=0.5  relative duration change for tgo diff to report
+                    a test as slower or faster, 0.5 is 50%
+  TGO_STILL_FAILING_RUNS=1  failures are tagged STILL FAILING when the
+                    test failed in this many previous runs in a row on
+                    the branch or its merge base, NEW otherwise
+  TGO_FLAKY_RUNS=20 failures are tagged KNOWN-FLAKY when the test passed
+                    and failed intermittently in this many previous runs
 
 `)
 
@@ -173,14 +355,52 @@ tgo settings:
 }
 
 func (f *Flags) printConfig(w io.Writer) {
-	fmt.Fprintf(w, `
-TGO config:
-  TGO_RESULTS: %s
-  TGO_SUMMARY: %s
-  TGO_RES_HIDE: %s
-
-`, f.Results.String(), f.Summary.String(), f.HideEmptyResults.String())
-
+	settings := []struct {
+		name  string
+		value interface{}
+	}{
+		{"TGO_V", int(f.V)},
+		{"TGO_BIN", f.Bin},
+		{"TGO_RESULTS", f.Results.String()},
+		{"TGO_SUMMARY", f.Summary.String()},
+		{"TGO_RES_HIDE", f.HideEmptyResults.String()},
+		{"TGO_TREE", f.Tree},
+		{"TGO_FAIL_PARENTS", f.FailParents},
+		{"TGO_UPDATE_EXAMPLES", f.UpdateExamples},
+		{"TGO_SOURCE", f.Source},
+		{"TGO_LINKS", f.Links},
+		{"TGO_LINK_TEMPLATE", f.LinkTemplate},
+		{"TGO_GROUPS", f.Groups},
+		{"TGO_RERUN", f.Rerun},
+		{"TGO_REPORT", f.Report},
+		{"TGO_LOG_LEVEL", f.LogLevel},
+		{"TGO_OUTPUT_HEAD", f.OutputHead},
+		{"TGO_OUTPUT_TAIL", f.OutputTail},
+		{"TGO_ARTIFACTS", f.Artifacts},
+		{"TGO_REDACT", f.Redact},
+		{"TGO_REDACT_REGEXP", f.RedactRegexp},
+		{"TGO_REDACT_ENV", f.RedactEnv},
+		{"TGO_BLAME", f.Blame},
+		{"TGO_OWNERS", f.Owners},
+		{"TGO_CODEOWNERS", f.CodeOwners},
+		{"TGO_HISTORY", f.History},
+		{"TGO_HISTORY_KEEP", f.HistoryKeep},
+		{"TGO_LAST_FAILED", f.LastFailed},
+		{"TGO_RETRIES", f.Retries},
+		{"TGO_FAIL_FLAKY", f.FailFlaky},
+		{"TGO_QUARANTINE", f.Quarantine},
+		{"TGO_QUARANTINE_RUNS", f.QuarantineRuns},
+		{"TGO_XFAIL", f.XFail},
+		{"TGO_STILL_FAILING_RUNS", f.StillFailingRuns},
+		{"TGO_FLAKY_RUNS", f.FlakyRuns},
+		{"TGO_DIFF_JSON", f.DiffJSON},
+		{"TGO_DIFF_DURATION", f.DiffDuration},
+	}
+	fmt.Fprint(w, "\nTGO config:\n")
+	for _, s := range settings {
+		fmt.Fprintf(w, "  %s: %v\n", s.name, s.value)
+	}
+	fmt.Fprint(w, "\n")
 }
 
 func (f *Flags) Setup(args []string) {
@@ -199,6 +419,9 @@ func (f *Flags) Setup(args []string) {
 				StatusPass,
 				StatusNone,
 				StatusFail,
+				StatusPanic,
+				StatusTimeout,
+				StatusRace,
 			}
 			f.HideEmptyResults = Statuses{
 				// StatusSkip,
@@ -210,6 +433,13 @@ func (f *Flags) Setup(args []string) {
 			f.Summary = Statuses{
 				StatusNone,
 				StatusFail,
+				StatusPanic,
+				StatusTimeout,
+				StatusRace,
+				StatusFlaky,
+				StatusQuarantined,
+				StatusXFail,
+				StatusXPass,
 				// StatusPass,
 			}
 		}
@@ -243,6 +473,17 @@ func (a Action) IsStatus(s Status) bool {
 
 type Actions []Action
 
+func (as Actions) Any(actions ...Action) bool {
+	for _, a := range as {
+		for _, action := range actions {
+			if a == action {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Status is mostly like Actions but only for end states including 'none' which
 // means that tests never reported as finished.
 type Status string
@@ -328,17 +569,16 @@ func (ss *Statuses) Set(value string) error {
 
 // Event .
 //
-//
 // The Action field is one of a fixed set of action descriptions:
-//    run    - the test has started running
-//    pause  - the test has been paused
-//    cont   - the test has continued running
-//    pass   - the test passed
-//    bench  - the benchmark printed log output but did not fail
-//    fail   - the test or benchmark failed
-//    output - the test printed output
-//    skip   - the test was skipped or the package contained no tests
 //
+//	run    - the test has started running
+//	pause  - the test has been paused
+//	cont   - the test has continued running
+//	pass   - the test passed
+//	bench  - the benchmark printed log output but did not fail
+//	fail   - the test or benchmark failed
+//	output - the test printed output
+//	skip   - the test was skipped or the package contained no tests
 type Event struct {
 	Time    time.Time // encodes as an RFC3339-format string
 	Action  Action
@@ -346,6 +586,10 @@ type Event struct {
 	Test    string
 	Elapsed float64 // seconds
 	Output  string
+
+	// OutputType is "error" and "error-continue" for t.Error output since
+	// go1.24, empty otherwise.
+	OutputType string `json:",omitempty"`
 }
 
 func (t Event) Key() Key {
@@ -368,6 +612,51 @@ func (t Key) String() string {
 	return t.Package + "." + t.Test
 }
 
+// TestName returns the test name, or the package for package keys.
+func (t Key) TestName() string {
+	if t.Test == "" {
+		return t.Package
+	}
+	return t.Test
+}
+
+// Depth returns the subtest nesting level, 0 for top level tests.
+func (t Key) Depth() int {
+	return strings.Count(t.Test, "/")
+}
+
+// Name returns the last element of the test name.
+func (t Key) Name() string {
+	if i := strings.LastIndex(t.Test, "/"); i >= 0 {
+		return t.Test[i+1:]
+	}
+	return t.Test
+}
+
+// Parent returns the key of the parent test. The parent of a top level test
+// is the package key.
+func (t Key) Parent() Key {
+	if i := strings.LastIndex(t.Test, "/"); i >= 0 {
+		return Key{Package: t.Package, Test: t.Test[:i]}
+	}
+	return Key{Package: t.Package}
+}
+
+// Root returns the key of the top level test t belongs to.
+func (t Key) Root() Key {
+	if i := strings.Index(t.Test, "/"); i >= 0 {
+		return Key{Package: t.Package, Test: t.Test[:i]}
+	}
+	return t
+}
+
+// IsSubtestOf reports whether t is a subtest of parent at any depth.
+func (t Key) IsSubtestOf(parent Key) bool {
+	return t.Package == parent.Package &&
+		parent.Test != "" &&
+		strings.HasPrefix(t.Test, parent.Test+"/")
+}
+
 type Events []Event
 
 func (es Events) Clone() Events {
@@ -383,6 +672,12 @@ func (es Events) Status() Status {
 		switch e.Action {
 
 		case ActionFail:
+			if p := es.FindPanic(); p != nil && !p.IsTimeout() {
+				return StatusPanic
+			}
+			if es.HasRace() {
+				return StatusRace
+			}
 			return StatusFail
 
 		case ActionPass:
@@ -458,6 +753,7 @@ loop:
 					(output == "PASS\n") ||
 					(output == "FAIL\n") ||
 					(output == "testing: warning: no tests to run\n") ||
+					(strings.HasPrefix(output, "-test.shuffle ")) ||
 					(strings.HasPrefix(outputWS, fmt.Sprintf("FAIL\t%s\t", e.Package))) ||
 					(strings.HasPrefix(outputWS, "coverage:") && strings.HasSuffix(outputWS, "of statements")))) {
 			continue loop
@@ -467,6 +763,25 @@ loop:
 	return v
 }
 
+// HasOwnOutput reports whether es contains output other than what go test
+// prints for every test and the result lines of its subtests.
+func (es Events) HasOwnOutput() bool {
+	for _, e := range es.Compact() {
+		if e.Action != ActionOutput || e.Test == "" {
+			continue
+		}
+		output := strings.TrimSpace(e.Output)
+		if output == "" ||
+			strings.HasPrefix(output, fmt.Sprintf("--- FAIL: %s/", e.Test)) ||
+			strings.HasPrefix(output, fmt.Sprintf("--- PASS: %s/", e.Test)) ||
+			strings.HasPrefix(output, fmt.Sprintf("--- SKIP: %s/", e.Test)) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
 func (es Events) IsPackageWithoutTest() bool {
 	for _, e := range es {
 		output := strings.TrimLeft(e.Output, " ")
@@ -503,11 +818,15 @@ func (es Events) FindCoverage() string {
 	return ""
 }
 
-func (es Events) PrintDetail(flags Flags) {
+// printDetail prints the header and output of es as status in rc. Subtests
+// printed at depth > 0 are indented and named by their last name element only,
+// suffix is added to the end of the header line.
+func (es Events) printDetail(rc *RunContext, flags Flags, status Status, depth int, suffix string) {
 	if len(es) == 0 {
 		return
 	}
-	events := es.Clone()
+	// output after the ending event is printed by PrintLateOutput
+	events := es.UntilEnd().Clone()
 	if flags.V <= V3 {
 		events = events.Compact()
 	}
@@ -525,15 +844,14 @@ loop:
 	}
 
 	events.SortByTime()
-	status := events.Status()
 	numberEvents := len(filteredEvents)
-	if numberEvents == 0 && flags.HideEmptyResults.Any(status) {
+	if numberEvents == 0 && depth == 0 && suffix == "" && flags.HideEmptyResults.Any(status) {
 		return
 	}
 	textColor := defaultColor
 	var event *Event
 	switch status {
-	case StatusFail:
+	case StatusFail, StatusPanic, StatusRace:
 		event = events.FindFirstByAction(ActionFail)
 		textColor = failColor
 	case StatusPass:
@@ -556,7 +874,11 @@ loop:
 		if numberEvents > 0 {
 			c = testColorBold
 		}
-		testName = "." + c(event.Test)
+		if depth > 0 {
+			testName = rc.Links.LinkTest(event.Key(), c(event.Key().Name()))
+		} else {
+			testName = "." + rc.Links.LinkTest(event.Key(), c(event.Test))
+		}
 	}
 
 	var sb strings.Builder
@@ -576,18 +898,49 @@ loop:
 		sb.WriteString("[no tests]")
 	}
 
+	if p := es.FindPanic(); p != nil && !p.IsTimeout() {
+		sb.WriteString("  ")
+		sb.WriteString(panicColorBold("panic: " + p.Value))
+	}
+
+	sb.WriteString(suffix)
+
+	indent := strings.Repeat("    ", depth)
 	statusColor := statusColors[status]
 	statusBold := statusColorsBold[status]
-	fmt.Print(statusBold("===") +
-		" " + statusBold(statusNames[status]) +
-		" " + statusColor(event.Package) + testName +
-		sb.String() +
-		"\n",
-	)
-	if len(filteredEvents) > 0 {
+	if depth > 0 {
+		fmt.Print(indent + statusBold("---") +
+			" " + statusBold(statusNames[status]) +
+			" " + testName +
+			sb.String() +
+			"\n",
+		)
+	} else {
+		fmt.Print(statusBold("===") +
+			" " + statusBold(statusNames[status]) +
+			" " + rc.Links.LinkPackage(event.Package, statusColor(event.Package)) + testName +
+			sb.String() +
+			"\n",
+		)
+	}
+	if len(filteredEvents) > 0 && depth == 0 {
 		fmt.Println("")
 	}
-	for _, e := range filteredEvents {
+	output := events
+	if flags.V <= V3 {
+		output = output.CollapseStacks(rc.Modules)
+	}
+	lines := output.Render(flags, textColor)
+	if flags.Source && flags.V <= V3 && FailureStatuses.Any(status) {
+		lines = rc.Sources.AddSnippets(flags, lines)
+	}
+	if FailureStatuses.Any(status) {
+		lines = rc.Blames.AddBlame(rc.Modules, es, lines)
+	}
+	lines = es.TruncateOutput(flags, rc.Links, lines)
+	lines = rc.Links.LinkRefs(lines)
+	for _, line := range lines {
+		e := line.Event
 		var ss []string
 		if flags.V >= V3 {
 			ss = append(ss, fmt.Sprintf("%7s", e.Action))
@@ -595,19 +948,204 @@ loop:
 		if flags.V >= V3 {
 			ss = append(ss, e.Time.Format("15:04:05.999"))
 		}
-		ss = append(ss, textColor(strings.TrimSuffix(e.Output, "\n")), "\n")
-		fmt.Print(strings.Join(ss, " "))
+		ss = append(ss, line.Text, "\n")
+		fmt.Print(indent + strings.Join(ss, " "))
 	}
-	if len(filteredEvents) > 0 {
+	if len(filteredEvents) > 0 && depth == 0 {
 		fmt.Println("")
 	}
 }
 
-type TestStorage map[Key]Events
+// TestStorage holds the events of each key of a run together with the
+// RunContext they are classified and printed in.
+type TestStorage struct {
+	*RunContext
+	Tests map[Key]Events
+
+	all       map[Key]Events // the events of every key, shared by subsets
+	statuses  map[Key]Status // the resolved status of keys, shared by subsets
+	panicking map[Key]Key    // top level tests whose output goes to a subtest
+}
+
+// NewTestStorage returns an empty TestStorage for rc, a nil rc classifies tests
+// by their events alone.
+func NewTestStorage(rc *RunContext) TestStorage {
+	if rc == nil {
+		rc = &RunContext{}
+	}
+	tests := make(map[Key]Events)
+	return TestStorage{
+		RunContext: rc,
+		Tests:      tests,
+		all:        tests,
+		statuses:   make(map[Key]Status),
+		panicking:  make(map[Key]Key),
+	}
+}
+
+// subset returns an empty TestStorage sharing the RunContext and the keys
+// tests are classified by of ts.
+func (ts TestStorage) subset() TestStorage {
+	subset := NewTestStorage(ts.RunContext)
+	subset.all = ts.all
+	subset.statuses = ts.statuses
+	return subset
+}
+
+// PrintDetail prints the details of key using its status and note in ts.
+// Failures are tagged as new or known from the failure history.
+func (ts TestStorage) PrintDetail(key Key, flags Flags) {
+	status := ts.StatusOf(key)
+	suffix := ts.failureTag(key, status)
+	if note := ts.Note(key); note != "" {
+		suffix += "  " + statusColors[status](note)
+	}
+	ts.Tests[key].printDetail(ts.RunContext, flags, status, 0, suffix)
+}
+
+// failureTag returns the colored tag of key from the failure history,
+// prefixed for a header suffix, "" unless status is a failure.
+func (ts TestStorage) failureTag(key Key, status Status) string {
+	if !FailureStatuses.Any(status) {
+		return ""
+	}
+	if tag := ts.FailureHistory.Tag(key); tag != "" {
+		return "  " + tagColor(tag)(tag)
+	}
+	return ""
+}
+
+// printResult prints the details of key when its status is one of
+// flags.Results, unless it failed only because of a subtest. Keys are added
+// to printed once handled.
+func (ts TestStorage) printResult(key Key, flags Flags, printed map[Key]bool) {
+	if !flags.Results.Any(ts.StatusOf(key)) {
+		return
+	}
+	printed[key] = true
+	if !flags.FailParents && ts.IsCascadingFailure(key) {
+		return
+	}
+	ts.PrintDetail(key, flags)
+}
+
+// StatusOf returns the status of key. Unlike Events.Status it takes the other
+// tests in ts into account, a test that never finished because another test in
+// the package panicked is reported as StatusPanic and tests that were running
+// when the package timed out as StatusTimeout. Failures that passed on a retry
+// are StatusFlaky and failures of quarantined tests StatusQuarantined.
+// Expected failures are StatusXFail and tests that were expected to fail but
+// passed StatusXPass.
+//
+// Statuses are resolved when a key ends and for all keys by ResolveStatuses,
+// the status of other keys is worked out on each call.
+func (ts TestStorage) StatusOf(key Key) Status {
+	if status, ok := ts.statuses[key]; ok {
+		return status
+	}
+	return ts.status(key)
+}
+
+// ResolveStatuses works out the status of every key once the run, retries
+// included, is over. Subtests are resolved before their parents, whose status
+// can depend on them.
+func (ts TestStorage) ResolveStatuses() {
+	clear(ts.statuses)
+	keys := make([]Key, 0, len(ts.all))
+	for key := range ts.all {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i].Test == "") != (keys[j].Test == "") {
+			return keys[j].Test == ""
+		}
+		return keys[i].Depth() > keys[j].Depth()
+	})
+	for _, key := range keys {
+		ts.statuses[key] = ts.status(key)
+	}
+}
+
+// status works out the status of key for StatusOf.
+func (ts TestStorage) status(key Key) Status {
+	// the status of key doesn't depend on the subset it is looked up in
+	ts.Tests = ts.all
+	status := ts.Tests[key].Status()
+	if status == StatusNone {
+		if p := ts.Tests[key].FindPanic(); p != nil && !p.IsTimeout() {
+			// panics in goroutines abort the binary before the test fails
+			status = StatusPanic
+		} else if ts.FindAbortingPanic(key) != nil {
+			status = StatusPanic
+		} else if ts.IsTimedOut(key) {
+			status = StatusTimeout
+		}
+	}
+	switch {
+	case LastFailedStatuses.Any(status) && ts.Retries.PassedOn(key) > 0:
+		return StatusFlaky
+	case LastFailedStatuses.Any(status) && ts.Quarantine.Find(key) != nil:
+		return StatusQuarantined
+	case FailureStatuses.Any(status) && ts.XFails.FindFailure(key, ts.Tests[key]) != nil:
+		return StatusXFail
+	case status == StatusPass && ts.XFails.Find(key) != nil:
+		return StatusXPass
+	case status == StatusFail:
+		if expected := ts.expectedFailure(key); expected != "" {
+			return expected
+		}
+	}
+	return status
+}
+
+// Note returns a short explanation of the status of key, ie. why it never
+// finished or which test leaked the goroutine it panicked in, or "" when there
+// is nothing to add.
+func (ts TestStorage) Note(key Key) string {
+	switch ts.StatusOf(key) {
+	case StatusFlaky:
+		return fmt.Sprintf("passed on retry %d", ts.Retries.PassedOn(key))
+	case StatusQuarantined:
+		if e := ts.Quarantine.Find(key); e != nil {
+			return "quarantined: " + e.String()
+		}
+	case StatusXPass:
+		return "expected to fail, remove it from " + ts.XFails.Filename
+	}
+	if p := ts.Tests[key].FindPanic(); p != nil {
+		if leak := p.GoroutineLeak(); leak != nil {
+			return "caused by a goroutine leaked by " + leak.Key.TestName()
+		}
+	}
+	if ts.Tests[key].FindFirstByAction(EndingActions...) != nil {
+		return ""
+	}
+	if p := ts.FindAbortingPanic(key); p != nil {
+		return "aborted by panic in " + p.Key.TestName()
+	}
+	if t := ts.FindPackageTimeout(key.Package); t != nil {
+		if ts.IsTimedOut(key) {
+			return "timed out after " + t.After
+		}
+		return "collateral of package timeout after " + t.After
+	}
+	return ""
+}
+
+// Notes returns the notes of all keys in ts that have one.
+func (ts TestStorage) Notes() map[Key]string {
+	notes := make(map[Key]string)
+	for key := range ts.Tests {
+		if note := ts.Note(key); note != "" {
+			notes[key] = note
+		}
+	}
+	return notes
+}
 
 func (ts TestStorage) OrderedKeys() []Key {
 	var tks []Key
-	for k := range ts {
+	for k := range ts.Tests {
 		tks = append(tks, k)
 	}
 	sort.SliceStable(tks, func(i, j int) bool {
@@ -623,48 +1161,54 @@ func (ts TestStorage) OrderedKeys() []Key {
 
 // Append event into tests
 func (ts TestStorage) Append(e Event) {
+	if ts.appendPanic(e) {
+		return
+	}
 	key := e.Key()
-	events, _ := ts[key]
+	events, _ := ts.Tests[key]
 	events = append(events, e)
-	ts[key] = events
+	ts.Tests[key] = events
+	if EndingActions.Any(e.Action) {
+		ts.statuses[key] = ts.status(key)
+	}
 }
 
 func (ts TestStorage) Union(values ...TestStorage) TestStorage {
-	tests := make(TestStorage, 0)
+	tests := ts.subset()
 	for _, values := range values {
-		for k, v := range values {
-			tests[k] = v
+		for k, v := range values.Tests {
+			tests.Tests[k] = v
 		}
 	}
 	return tests
 }
 
 func (ts TestStorage) FilterPackageResults() TestStorage {
-	tests := make(TestStorage, 0)
-	for key, events := range ts {
+	tests := ts.subset()
+	for key, events := range ts.Tests {
 		if key.Test != "" {
-			tests[key] = events
+			tests.Tests[key] = events
 		}
 	}
 	return tests
 }
 
 func (ts TestStorage) FindPackageResults() TestStorage {
-	tests := make(TestStorage, 0)
-	for key, events := range ts {
+	tests := ts.subset()
+	for key, events := range ts.Tests {
 		if key.Test == "" {
-			tests[key] = events
+			tests.Tests[key] = events
 		}
 	}
 	return tests
 }
 
 func (ts TestStorage) FilterKeys(exclude map[Key]bool) TestStorage {
-	tests := make(TestStorage, 0)
+	tests := ts.subset()
 loop:
-	for key, events := range ts {
+	for key, events := range ts.Tests {
 		if !exclude[key] {
-			tests[key] = events
+			tests.Tests[key] = events
 			continue loop
 		}
 	}
@@ -672,24 +1216,77 @@ loop:
 }
 
 func (ts TestStorage) FindPackageTests(name string) TestStorage {
-	tests := make(TestStorage, 0)
+	tests := ts.subset()
 loop:
-	for key, events := range ts {
+	for key, events := range ts.Tests {
 		if name == key.Package {
-			tests[key] = events
+			tests.Tests[key] = events
 			continue loop
 		}
 	}
 	return tests
 }
 
+// FindSubtests returns the subtests of key at any depth.
+func (ts TestStorage) FindSubtests(key Key) TestStorage {
+	tests := ts.subset()
+	for k, events := range ts.Tests {
+		if k.IsSubtestOf(key) {
+			tests.Tests[k] = events
+		}
+	}
+	return tests
+}
+
+// IsCascadingFailure reports whether key failed only because one of its
+// subtests failed.
+func (ts TestStorage) IsCascadingFailure(key Key) bool {
+	if key.Test == "" {
+		return false
+	}
+	events := ts.all[key]
+	if events.Status() != StatusFail || events.HasOwnOutput() {
+		return false
+	}
+	// the subtests may not be in ts when it is a subset
+	for k, events := range ts.all {
+		if k.IsSubtestOf(key) && FailureStatuses.Any(events.Status()) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterCascadingFailures removes tests that failed only because one of
+// their subtests failed, leaving the failing subtests as the root causes.
+func (ts TestStorage) FilterCascadingFailures() TestStorage {
+	tests := ts.subset()
+	for key, events := range ts.Tests {
+		if !ts.IsCascadingFailure(key) {
+			tests.Tests[key] = events
+		}
+	}
+	return tests
+}
+
+// FindByStatus returns the tests whose StatusOf is one of statuses.
+func (ts TestStorage) FindByStatus(statuses ...Status) TestStorage {
+	tests := ts.subset()
+	for key, events := range ts.Tests {
+		if Statuses(statuses).Any(ts.StatusOf(key)) {
+			tests.Tests[key] = events
+		}
+	}
+	return tests
+}
+
 func (ts TestStorage) FindByAction(action Action) TestStorage {
-	tests := make(TestStorage, 0)
+	tests := ts.subset()
 loop:
-	for key, events := range ts {
+	for key, events := range ts.Tests {
 		for _, e := range events {
 			if e.Action == action {
-				tests[key] = events
+				tests.Tests[key] = events
 				continue loop
 			}
 		}
@@ -702,48 +1299,48 @@ func (ts TestStorage) FilterAction(actions ...Action) TestStorage {
 	for _, action := range actions {
 		actionMatch[action] = true
 	}
-	tests := make(TestStorage, 0)
+	tests := ts.subset()
 loop:
-	for key, events := range ts {
+	for key, events := range ts.Tests {
 		for _, e := range events {
 			if actionMatch[e.Action] {
 				continue loop
 			}
 		}
-		tests[key] = events
+		tests.Tests[key] = events
 	}
 	return tests
 }
 
 func (ts TestStorage) WithCoverage() TestStorage {
-	tests := make(TestStorage, 0)
+	tests := ts.subset()
 loop:
-	for key, events := range ts {
+	for key, events := range ts.Tests {
 		if key.Test != "" || key.Package == "" {
 			continue loop
 		}
 		cov := events.FindCoverage()
 		if cov != "" {
-			tests[key] = events
+			tests.Tests[key] = events
 		}
 	}
 	return tests
 }
 
 func (ts TestStorage) FilterNotests() TestStorage {
-	tests := make(TestStorage, 0)
+	tests := ts.subset()
 loop:
-	for key, events := range ts {
+	for key, events := range ts.Tests {
 		if events.IsPackageWithoutTest() {
 			continue loop
 		}
-		tests[key] = events
+		tests.Tests[key] = events
 	}
 	return tests
 }
 
 func (ts TestStorage) CountTests() int {
-	return len(ts.FilterPackageResults())
+	return len(ts.FilterPackageResults().Tests)
 }
 
 func (ts TestStorage) PrintShortSummary(status Status) {
@@ -757,7 +1354,7 @@ func (ts TestStorage) PrintShortSummary(status Status) {
 
 	fmt.Println(hr, header, hr)
 	for _, key := range tests.OrderedKeys() {
-		events := ts[key]
+		events := ts.Tests[key]
 
 		var sb strings.Builder
 
@@ -789,7 +1386,7 @@ func (ts TestStorage) PrintShortSummary(status Status) {
 	}
 }
 
-func (ts TestStorage) PrintSummary(status Status) {
+func (ts TestStorage) PrintSummary(status Status, notes map[Key]string) {
 	// count := ts.CountTests()
 	statusColor := statusColors[status]
 	header := statusColor(statusNames[status])
@@ -798,7 +1395,7 @@ func (ts TestStorage) PrintSummary(status Status) {
 
 	fmt.Println(hr, header, hr)
 	for _, key := range ts.OrderedKeys() {
-		events := ts[key]
+		events := ts.Tests[key]
 
 		var sb strings.Builder
 
@@ -806,6 +1403,14 @@ func (ts TestStorage) PrintSummary(status Status) {
 			sb.WriteString("  ")
 			sb.WriteString(timeColor(fmt.Sprintf("(%.2fs)", fe.Elapsed)))
 		}
+		if p := events.FindPanic(); p != nil && status == StatusPanic {
+			sb.WriteString("  ")
+			sb.WriteString(statusColor("panic: " + p.Value))
+		}
+		if note := notes[key]; note != "" {
+			sb.WriteString("  ")
+			sb.WriteString(statusColor(note))
+		}
 		if key.Test == "" {
 			if events.IsPackageWithoutTest() {
 				sb.WriteString("  ")
@@ -817,14 +1422,14 @@ func (ts TestStorage) PrintSummary(status Status) {
 				sb.WriteString(coverColor(fmt.Sprintf("{%s}", coverage)))
 			}
 			fmt.Print(prefix +
-				packageColor(key.Package) +
+				ts.Links.LinkPackage(key.Package, packageColor(key.Package)) +
 				sb.String() +
 				"\n",
 			)
 		} else {
 			fmt.Print(prefix +
-				packageColor(key.Package) +
-				"." + testColor(key.Test) +
+				ts.Links.LinkPackage(key.Package, packageColor(key.Package)) +
+				"." + ts.Links.LinkTest(key, testColor(key.Test)) +
 				sb.String() +
 				"\n",
 			)
@@ -838,7 +1443,7 @@ func (ts TestStorage) PrintCoverage() {
 
 	fmt.Println(hr, coverColor("COVR"), hr)
 	for _, key := range ts.OrderedKeys() {
-		events := ts[key]
+		events := ts.Tests[key]
 		if key.Test == "" {
 			coverage := events.FindCoverage()
 			if len(coverage) > 0 {
@@ -853,6 +1458,23 @@ func (ts TestStorage) PrintCoverage() {
 	}
 }
 
+// OnlyExpectedFailures reports whether go test failed only because of tests
+// that passed on a retry, are quarantined or are expected to fail. It is false
+// when go test failed without failing a test, ie. for build and usage errors.
+func (ts TestStorage) OnlyExpectedFailures(flags Flags) bool {
+	if len(ts.Tests) == 0 || len(ts.RerunKeys(flags)) > 0 {
+		return false
+	}
+	if len(ts.FindByStatus(StatusXPass).Tests) > 0 {
+		return false
+	}
+	flaky := len(ts.FindByStatus(StatusFlaky).Tests) > 0
+	if flaky && flags.FailFlaky {
+		return false
+	}
+	return flaky || len(ts.FindByStatus(StatusQuarantined, StatusXFail).Tests) > 0
+}
+
 type ExitError int
 
 func (e ExitError) Error() string {
@@ -912,7 +1534,33 @@ func main() {
 		}
 	}()
 
-	if err := run(ctx, flags, os.Args[1:]); err != nil {
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "diff" {
+		if len(args) != 3 {
+			fmt.Println("usage: tgo diff <run A> <run B>")
+			os.Exit(2)
+		}
+		if err := Diff(ctx, flags, args[1], args[2]); err != nil {
+			var ee ExitError
+			if errors.As(err, &ee) {
+				os.Exit(int(ee))
+			}
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "last-failed" {
+		flags.LastFailed = true
+		args = args[1:]
+	}
+
+	err := run(ctx, flags, args)
+	if errors.Is(err, errFullRun) {
+		flags.LastFailed = false
+		err = run(ctx, flags, args)
+	}
+	if err != nil {
 		var ee ExitError
 		if errors.As(err, &ee) {
 			os.Exit(int(ee))
@@ -922,6 +1570,11 @@ func main() {
 	}
 }
 
+// errFullRun is returned by run when tgo last-failed is to be followed by a
+// run of the full set of tests. The run that returns it cancels its context
+// once go test is done, so the full run starts from the context of main.
+var errFullRun = errors.New("run the full set of tests")
+
 func run(ctx context.Context, flags Flags, argv []string) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -933,27 +1586,98 @@ func run(ctx context.Context, flags Flags, argv []string) error {
 		}
 	}
 
-	args := []string{"test", "-json"}
-	args = append(args, argv...)
-	log.Println("args", args)
-	cmd := exec.CommandContext(ctx, flags.Bin, args...)
-	cmd.Stderr = os.Stderr
+	rc := &RunContext{
+		Modules: findModules(ctx, flags.Bin),
+		Sources: NewSourceCache(ctx, flags.Bin),
+	}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
+	if flags.LogLevel != "" {
+		if _, err := ParseLogLevel(flags.LogLevel); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if rc.Redactor, err = NewRedactor(flags.Redact, flags.RedactRegexp, flags.RedactEnv); err != nil {
 		return err
 	}
-	defer stdout.Close()
 
-	if err := cmd.Start(); err != nil {
-		fmt.Println(err)
+	rc.Links, err = NewLinker(flags.Links, flags.LinkTemplate, rc.Sources)
+	if err != nil {
 		return err
 	}
+	if flags.Owners {
+		filename := flags.CodeOwners
+		if filename == "" {
+			if wd, err := os.Getwd(); err == nil {
+				filename = FindCodeOwners(wd)
+			}
+		}
+		if filename != "" {
+			if rc.CodeOwners, err = ReadCodeOwners(filename, rc.Sources); err != nil {
+				return err
+			}
+		}
+	}
+	quarantineFile := flags.Quarantine
+	if quarantineFile == "" {
+		if wd, err := os.Getwd(); err == nil {
+			quarantineFile = findUp(wd, ".tgo-quarantine")
+		}
+	}
+	if quarantineFile != "" {
+		if rc.Quarantine, err = ReadQuarantine(quarantineFile); err != nil {
+			return err
+		}
+	}
+
+	xfailFile := flags.XFail
+	if xfailFile == "" {
+		if wd, err := os.Getwd(); err == nil {
+			xfailFile = findUp(wd, ".tgo-xfail")
+		}
+	}
+	if xfailFile != "" {
+		if rc.XFails, err = ReadXFails(xfailFile); err != nil {
+			return err
+		}
+	}
+
+	if flags.History {
+		rc.FailureHistory = NewFailureHistory(ctx, flags, rc.Modules)
+	}
+
+	if flags.Blame {
+		rc.Blames = NewBlameCache(ctx, rc.Sources)
+	}
+
+	argvs := [][]string{argv}
+	if flags.LastFailed {
+		if argvs, err = LastFailed(rc.Modules, argv); err != nil {
+			return err
+		}
+		if len(argvs) == 0 {
+			fmt.Println(passColorBold("no tests failed in the last run"))
+			if offerFullRun() {
+				return errFullRun
+			}
+			return nil
+		}
+	}
+	log.Println("args", argvs)
+
+	stdout, wait := goTest(ctx, flags.Bin, argvs)
+	defer stdout.Close()
 
 	t0 := time.Now()
 
-	tests := make(TestStorage, 0)
+	tests := NewTestStorage(rc)
 	printed := make(map[Key]bool, 0)
+	deferred := make(keySet)
+	// source snippets need the package directories from go list, which
+	// would hold up reading the output of go test, so failures with
+	// snippets are printed once go test is done.
+	var withSource []Key
 	scanner := bufio.NewScanner(stdout)
 
 	fmt.Println("*****")
@@ -961,81 +1685,243 @@ scan:
 	for scanner.Scan() {
 
 		var e Event
-		log.Println("LINE:", scanner.Text())
+		if flags.V > V3 {
+			// redacting every line is only worth it when it is logged
+			log.Println("LINE:", rc.Redactor.Redact(scanner.Text()))
+		}
 		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
 			log.Println("scanner error", err)
 			continue scan
 		}
+		e = rc.Redactor.RedactEvent(e)
 		tests.Append(e)
 		key := e.Key()
-		if !printed[key] && flags.Results.HasAction(e.Action) {
-			tests[key].PrintDetail(flags)
-			printed[key] = true
+		if printed[key] || !EndingActions.Any(e.Action) {
+			continue scan
+		}
+		if flags.Tree && key.Test != "" {
+			// subtests end before their parent so the whole tree is
+			// printed once the top level test ends.
+			if key.Depth() == 0 {
+				tree := tests.Tree(key)
+				if flags.Source && FailureStatuses.Any(tree.Status()) {
+					withSource = append(withSource, key)
+				} else if flags.Results.Any(tree.Status()) {
+					tests.PrintTree(tree, flags)
+					for _, k := range tree.Keys() {
+						printed[k] = true
+					}
+				}
+			}
+			continue scan
+		}
+		if key.Depth() > 0 && tests.Tests[key].Status() == StatusFail && tests.Tests[key].failedSilently() {
+			// a panic is reported after the top level test failed
+			deferred[key] = true
+			continue scan
 		}
+		var keys []Key
+		if key.Test != "" && key.Depth() == 0 {
+			keys = deferred.Pop(key)
+		}
+		for _, key := range append(keys, key) {
+			if flags.Source && FailureStatuses.Any(tests.StatusOf(key)) {
+				withSource = append(withSource, key)
+				continue
+			}
+			tests.printResult(key, flags, printed)
+		}
+	}
+	withSource = append(withSource, deferred.Pop(Key{})...)
+	tests.ResolveStatuses()
+	// look up all package directories at once now that go test is done,
+	// links are plain text until then
+	var pkgs []string
+	if rc.Links != nil {
+		pkgs = tests.Packages()
+	} else if flags.Source {
+		for _, key := range withSource {
+			pkgs = append(pkgs, key.Package)
+		}
+	}
+	rc.Sources.Load(pkgs)
+	for _, key := range withSource {
+		if flags.Tree && key.Test != "" {
+			tree := tests.Tree(key)
+			if flags.Results.Any(tree.Status()) {
+				tests.PrintTree(tree, flags)
+				for _, k := range tree.Keys() {
+					printed[k] = true
+				}
+			}
+			continue
+		}
+		tests.printResult(key, flags, printed)
 	}
 
-	if len(tests) > 0 {
-		if flags.Results.Any(StatusNone) {
-			noneTests := tests.
-				FilterKeys(printed).
-				FilterAction(EndingActions...)
-			for _, key := range noneTests.OrderedKeys() {
-				tests[key].PrintDetail(flags)
-				printed[key] = true
+	if len(tests.Tests) > 0 {
+		noneTests := tests.
+			FilterKeys(printed).
+			FilterAction(EndingActions...)
+		for _, key := range noneTests.OrderedKeys() {
+			if printed[key] || !flags.Results.Any(tests.StatusOf(key)) {
+				continue
+			}
+			if flags.Tree && key.Test != "" {
+				tree := tests.Tree(key.Root())
+				tests.PrintTree(tree, flags)
+				for _, k := range tree.Keys() {
+					printed[k] = true
+				}
+				continue
 			}
+			tests.PrintDetail(key, flags)
+			printed[key] = true
 		}
 
+		if flags.Retries > 0 {
+			tests.Retries = tests.Retry(ctx, flags, argv)
+			tests.ResolveStatuses()
+		}
+
+		tests.PrintLateOutput(flags)
+
 		// print summaries
+		notes := tests.Notes()
 		for _, status := range flags.Summary {
-			if status == StatusNone {
-				filtered := tests.FilterAction(EndingActions...)
-				if len(filtered) > 0 {
-					filtered.PrintSummary(status)
-				}
+			filtered := tests.FindByStatus(status)
 
-			} else {
-				for _, action := range EndingActions {
-					if status.IsAction(action) {
+			if status == StatusTimeout {
+				if len(filtered.Tests) > 0 {
+					tests.PrintTimeouts(flags)
+				}
+				continue
+			}
 
-						filtered := tests.FindByAction(action)
+			if status == StatusRace {
+				if len(filtered.Tests) > 0 {
+					tests.PrintRaces()
+				}
+				continue
+			}
 
-						if action == ActionSkip {
-							if flags.V <= V3 {
-								filtered = filtered.FilterNotests()
-							}
-						}
+			if status == StatusQuarantined {
+				tests.PrintQuarantine(flags)
+				continue
+			}
 
-						if len(filtered) > 0 {
-							filtered.PrintSummary(status)
-						}
+			if (status == StatusFail || status == StatusFlaky || status == StatusXFail) && !flags.FailParents {
+				filtered = filtered.FilterCascadingFailures()
+			}
 
-					}
+			if status == StatusSkip {
+				if flags.V <= V3 {
+					filtered = filtered.FilterNotests()
+				}
+				if len(filtered.Tests) > 0 {
+					filtered.PrintSummary(status, filtered.SkipReasons())
+					filtered.PrintSkipGroups(flags)
 				}
+				continue
+			}
+
+			if len(filtered.Tests) > 0 {
+				filtered.PrintSummary(status, notes)
+			}
+		}
+
+		tests.CodeOwners.PrintFailures(tests.Links, tests.RerunKeys(flags))
+
+		if flags.Rerun {
+			if keys := tests.RerunKeys(flags); len(keys) > 0 {
+				tests.PrintRerun(flags, argv, keys)
+			}
+		}
+
+		if flags.Groups {
+			failed := tests.FindByStatus(FailureStatuses...)
+			if !flags.FailParents {
+				failed = failed.FilterCascadingFailures()
+			}
+			failed.PrintFailureGroups(flags)
+		}
+
+		if flags.Report != "" {
+			if err := tests.WriteReport(flags, argv, flags.Report); err != nil {
+				fmt.Println("error writing report:", err)
+			}
+		}
+
+		if flags.History {
+			if err := tests.SaveHistory(ctx, flags, argv); err != nil {
+				fmt.Println("error saving history:", err)
+			}
+		}
+
+		if flags.UpdateExamples {
+			if err := tests.UpdateExamples(ctx, flags); err != nil {
+				fmt.Println("error updating examples:", err)
 			}
 		}
 
 		if coverEnabled {
 			filtered := tests.WithCoverage()
-			if len(filtered) > 0 {
+			if len(filtered.Tests) > 0 {
 				filtered.PrintCoverage()
 			}
 		}
 
 		{
-			allFail := tests.FindByAction(ActionFail)
-			allPass := tests.FindByAction(ActionPass)
-			allSkip := tests.FindByAction(ActionSkip)
-			allNone := tests.FilterAction(EndingActions...)
+			allFail := tests.FindByStatus(StatusFail)
+			if !flags.FailParents {
+				allFail = allFail.FilterCascadingFailures()
+			}
+			allPass := tests.FindByStatus(StatusPass)
+			allSkip := tests.FindByStatus(StatusSkip)
+			allNone := tests.FindByStatus(StatusNone)
+			allPanic := tests.FindByStatus(StatusPanic)
+			allTimeout := tests.FindByStatus(StatusTimeout)
+			allRace := tests.FindByStatus(StatusRace)
+			allFlaky := tests.FindByStatus(StatusFlaky)
+			if !flags.FailParents {
+				allFlaky = allFlaky.FilterCascadingFailures()
+			}
+			allXFail := tests.FindByStatus(StatusXFail)
+			if !flags.FailParents {
+				allXFail = allXFail.FilterCascadingFailures()
+			}
+			allXPass := tests.FindByStatus(StatusXPass)
 
 			countPass := allPass.CountTests()
 			countFail := allFail.CountTests()
-			countNone := len(allNone)
+			countNone := len(allNone.Tests)
 			countSkip := allSkip.CountTests()
+			countPanic := allPanic.CountTests()
+			countTimeout := allTimeout.CountTests()
+			countRace := allRace.CountTests()
+			countFlaky := allFlaky.CountTests()
+			countXFail := allXFail.CountTests()
+			countXPass := allXPass.CountTests()
 
 			pass := statusNames[StatusPass] + ":" + fmt.Sprint(countPass)
 			fail := statusNames[StatusFail] + ":" + fmt.Sprint(countFail)
+			if countFail > 0 && tests.FailureHistory.HasRuns() {
+				countNew := 0
+				for key := range allFail.FilterPackageResults().Tests {
+					if tests.FailureHistory.Tag(key) == TagNew {
+						countNew++
+					}
+				}
+				fail += fmt.Sprintf(" (new:%d existing:%d)", countNew, countFail-countNew)
+			}
 			none := statusNames[StatusNone] + ":" + fmt.Sprint(countNone)
 			skip := statusNames[StatusSkip] + ":" + fmt.Sprint(countSkip)
+			panics := statusNames[StatusPanic] + ":" + fmt.Sprint(countPanic)
+			timeouts := statusNames[StatusTimeout] + ":" + fmt.Sprint(countTimeout)
+			races := statusNames[StatusRace] + ":" + fmt.Sprint(countRace)
+			flakes := statusNames[StatusFlaky] + ":" + fmt.Sprint(countFlaky)
+			xfail := statusNames[StatusXFail] + ":" + fmt.Sprint(countXFail)
+			xpass := statusNames[StatusXPass] + ":" + fmt.Sprint(countXPass)
 
 			statusColor := hardLineColor
 
@@ -1044,16 +1930,45 @@ scan:
 				pass = statusColor(pass)
 			}
 
+			if countFlaky > 0 {
+				statusColor = flakyColorBold
+				flakes = statusColor(flakes)
+			}
+
+			if countXFail > 0 {
+				xfail = xfailColorBold(xfail)
+			}
+
 			if countNone > 0 {
 				statusColor = noneColorBold
 				none = statusColor(none)
 			}
 
+			if countTimeout > 0 {
+				statusColor = timeoutColorBold
+				timeouts = statusColor(timeouts)
+			}
+
+			if countRace > 0 {
+				statusColor = raceColorBold
+				races = statusColor(races)
+			}
+
 			if countFail > 0 {
 				statusColor = failColorBold
 				fail = statusColor(fail)
 			}
 
+			if countXPass > 0 {
+				statusColor = failColorBold
+				xpass = xpassColorBold(xpass)
+			}
+
+			if countPanic > 0 {
+				statusColor = panicColorBold
+				panics = statusColor(panics)
+			}
+
 			// if countSkip > 0 {
 			// skip = skipColorBold(skip)
 			// }
@@ -1063,8 +1978,26 @@ scan:
 			status := statusColor("══════") + " " +
 				statusColor(time.Now().Format("15:04:05")) +
 				sep + pass +
-				sep + fail +
-				sep + none +
+				sep + fail
+			if countPanic > 0 {
+				status += sep + panics
+			}
+			if countTimeout > 0 {
+				status += sep + timeouts
+			}
+			if countRace > 0 {
+				status += sep + races
+			}
+			if countFlaky > 0 {
+				status += sep + flakes
+			}
+			if countXFail > 0 {
+				status += sep + xfail
+			}
+			if countXPass > 0 {
+				status += sep + xpass
+			}
+			status += sep + none +
 				sep + skip +
 				sep + statusColor(time.Now().Sub(t0).Round(time.Millisecond).String()) +
 				"  " + statusColor("══════")
@@ -1083,12 +2016,28 @@ scan:
 		time.Sleep(2 * time.Second)
 		cancel()
 	}()
-	cmdErr := cmd.Wait()
+	cmdErr := wait()
+	xpass := len(tests.FindByStatus(StatusXPass).Tests) > 0
 	var ee *exec.ExitError
 	if cmdErr != nil && errors.As(cmdErr, &ee) {
+		if tests.OnlyExpectedFailures(flags) {
+			return nil
+		}
 		if ee.Exited() {
 			return ExitError(ee.ExitCode())
 		}
+	} else if cmdErr != nil {
+		fmt.Println(cmdErr)
+		return cmdErr
+	}
+	if xpass {
+		return ExitError(1)
+	}
+	if flags.LastFailed && cmdErr == nil {
+		fmt.Println(passColorBold("all the tests that failed in the last run pass now"))
+		if offerFullRun() {
+			return errFullRun
+		}
 	}
 	return nil
 }
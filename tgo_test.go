@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadTests reads the go test -json output saved in testdata/name.
+func loadTests(t *testing.T, name string) TestStorage {
+	t.Helper()
+	tests := appendTests(t, name)
+	tests.ResolveStatuses()
+	return tests
+}
+
+// appendTests appends the events saved in testdata/name to a TestStorage
+// without resolving the statuses once the stream is over.
+func appendTests(t *testing.T, name string) TestStorage {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tests := NewTestStorage(nil)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		tests.Append(e)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return tests
+}
+
+func TestResolveStatuses(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range files {
+		name := filepath.Base(filename)
+		t.Run(name, func(t *testing.T) {
+			tests := appendTests(t, name)
+			// resolved as the keys ended
+			ended := maps.Clone(tests.statuses)
+			tests.ResolveStatuses()
+			for key, status := range ended {
+				if want := tests.StatusOf(key); status != want {
+					t.Errorf("%v: resolved %v when it ended, want %v", key, status, want)
+				}
+			}
+			for key := range tests.Tests {
+				if _, ok := tests.statuses[key]; !ok {
+					t.Errorf("%v: not resolved", key)
+				}
+			}
+		})
+	}
+}
+
+func TestIsCascadingFailure(t *testing.T) {
+	tests := loadTests(t, "cascade.json")
+	pkg := "example.com/ex/cascade"
+	for _, tc := range []struct {
+		test string
+		want bool
+	}{
+		{"TestParent", true},
+		{"TestParent/bad", false},
+		{"TestParent/ok", false},
+		{"TestOwn", false},
+		{"TestOwn/bad", false},
+		{"", false},
+	} {
+		key := Key{Package: pkg, Test: tc.test}
+		if got := tests.IsCascadingFailure(key); got != tc.want {
+			t.Errorf("IsCascadingFailure(%s) = %v, want %v", key, got, tc.want)
+		}
+	}
+}
+
+func TestFilterCascadingFailures(t *testing.T) {
+	tests := loadTests(t, "cascade.json")
+	failed := tests.FindByStatus(StatusFail).FilterCascadingFailures()
+	pkg := "example.com/ex/cascade"
+	for _, test := range []string{"", "TestParent/bad", "TestOwn", "TestOwn/bad"} {
+		if _, ok := failed.Tests[Key{Package: pkg, Test: test}]; !ok {
+			t.Errorf("%q was filtered", test)
+		}
+	}
+	if _, ok := failed.Tests[Key{Package: pkg, Test: "TestParent"}]; ok {
+		t.Error("TestParent was not filtered")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Timeout is a test binary killed by the -timeout flag.
+type Timeout struct {
+	Package string
+	After   string        // the -timeout value, ie. "10m0s"
+	Running []RunningTest // tests that were running, empty before go1.21
+	Key     Key           // the key the timeout panic was reported for
+}
+
+// RunningTest is a test that was running when the timeout fired.
+type RunningTest struct {
+	Test    string
+	Running string // how long the test had been running, ie. "9m59s"
+}
+
+// Find returns the running test named test, nil if it wasn't running.
+func (t *Timeout) Find(test string) *RunningTest {
+	for _, r := range t.Running {
+		if r.Test == test {
+			return &r
+		}
+	}
+	return nil
+}
+
+// IsTimeout reports whether p is the panic go test uses to stop a test binary
+// that ran longer than -timeout.
+func (p *Panic) IsTimeout() bool {
+	return strings.HasPrefix(p.Value, "test timed out after ")
+}
+
+// FindTimeout finds a test timeout panic in the output of es.
+func (es Events) FindTimeout() *Timeout {
+	lines := es.outputLines()
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "panic: test timed out after ") {
+			continue
+		}
+		t := &Timeout{
+			Package: es[0].Package,
+			After:   strings.TrimPrefix(line, "panic: test timed out after "),
+			Key:     es[0].Key(),
+		}
+		if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) == "running tests:" {
+			for _, line := range lines[i+2:] {
+				if !strings.HasPrefix(line, "\t\t") {
+					break
+				}
+				test, running, _ := strings.Cut(strings.TrimSpace(line), " (")
+				t.Running = append(t.Running, RunningTest{
+					Test:    test,
+					Running: strings.TrimSuffix(running, ")"),
+				})
+			}
+		}
+		return t
+	}
+	return nil
+}
+
+// FindPackageTimeout returns the timeout of pkg, nil if it didn't time out.
+func (ts TestStorage) FindPackageTimeout(pkg string) *Timeout {
+	tests := ts.FindPackageTests(pkg)
+	for _, key := range tests.OrderedKeys() {
+		if t := tests.Tests[key].FindTimeout(); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// IsTimedOut reports whether key was one of the tests running when its
+// package timed out. Without a list of running tests the test the timeout was
+// reported for is assumed to be the one running.
+func (ts TestStorage) IsTimedOut(key Key) bool {
+	if key.Test == "" || ts.Tests[key].FindFirstByAction(EndingActions...) != nil {
+		return false
+	}
+	t := ts.FindPackageTimeout(key.Package)
+	if t == nil {
+		return false
+	}
+	if len(t.Running) == 0 {
+		return t.Key == key
+	}
+	return t.Find(key.Test) != nil
+}
+
+// LastOutput returns up to n of the last output lines of es printed before a
+// timeout, excluding what go test prints for every test.
+func (es Events) LastOutput(n int) []string {
+	var lines []string
+	for _, e := range es.Compact() {
+		if e.Action != ActionOutput {
+			continue
+		}
+		output := strings.TrimSuffix(e.Output, "\n")
+		if strings.HasPrefix(output, "panic: test timed out after ") {
+			break
+		}
+		if strings.TrimSpace(output) != "" {
+			lines = append(lines, output)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// PrintTimeouts prints the tests that were running when their package timed
+// out together with their last lines of output.
+func (ts TestStorage) PrintTimeouts(flags Flags) {
+	statusColor := statusColors[StatusTimeout]
+	statusBold := statusColorsBold[StatusTimeout]
+	hr := statusColor("════════════")
+	prefix := statusColor(fmt.Sprintf("%6s ", statusNames[StatusTimeout]))
+
+	n := 3
+	if flags.V >= V2 {
+		n = 10
+	}
+
+	fmt.Println(hr, statusBold(statusNames[StatusTimeout]), hr)
+	for _, key := range ts.OrderedKeys() {
+		if !ts.IsTimedOut(key) {
+			continue
+		}
+		t := ts.FindPackageTimeout(key.Package)
+		var sb strings.Builder
+		if r := t.Find(key.Test); r != nil {
+			sb.WriteString("  ")
+			sb.WriteString(timeColor(fmt.Sprintf("(running %s)", r.Running)))
+		}
+		sb.WriteString("  ")
+		sb.WriteString(statusColor("timed out after " + t.After))
+		fmt.Print(prefix +
+			packageColor(key.Package) +
+			"." + testColor(key.Test) +
+			sb.String() +
+			"\n",
+		)
+		for _, line := range ts.Tests[key].LastOutput(n) {
+			fmt.Println("       " + line)
+		}
+	}
+}
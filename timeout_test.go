@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindTimeout(t *testing.T) {
+	key := Key{Package: "example.com/p", Test: "TestA"}
+	for _, tc := range []struct {
+		name   string
+		output []string
+		want   *Timeout
+	}{
+		{
+			name:   "none",
+			output: []string{"=== RUN   TestX", "--- PASS: TestX (0.00s)"},
+		},
+		{
+			name: "running tests",
+			output: []string{
+				"panic: test timed out after 10m0s",
+				"\trunning tests:",
+				"\t\tTestA (9m59s)",
+				"\t\tTestB/sub (10m0s)",
+				"",
+				"goroutine 10 [running]:",
+			},
+			want: &Timeout{
+				Package: key.Package,
+				After:   "10m0s",
+				Running: []RunningTest{{"TestA", "9m59s"}, {"TestB/sub", "10m0s"}},
+				Key:     key,
+			},
+		},
+		{
+			// before go1.21 the running tests aren't listed
+			name: "old go",
+			output: []string{
+				"panic: test timed out after 1s",
+				"",
+				"goroutine 10 [running]:",
+			},
+			want: &Timeout{
+				Package: key.Package,
+				After:   "1s",
+				Key:     key,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := outputEvents(key, tc.output...).FindTimeout()
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsTimedOut(t *testing.T) {
+	tests := loadTests(t, "timeout.json")
+	pkg := "example.com/ex/timeout"
+	for _, tc := range []struct {
+		test string
+		want bool
+	}{
+		{"TestDone", false},
+		{"TestParallel", false},
+		{"TestParallel/a", true},
+		{"TestParallel/b", false},
+		{"TestNotStarted", false},
+		{"", false},
+	} {
+		key := Key{Package: pkg, Test: tc.test}
+		if got := tests.IsTimedOut(key); got != tc.want {
+			t.Errorf("IsTimedOut(%s) = %v, want %v", key, got, tc.want)
+		}
+	}
+	key := Key{Package: pkg, Test: "TestParallel/a"}
+	if got, want := tests.Tests[key].LastOutput(3), []string{"    timeout_test.go:13: waiting"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LastOutput = %q, want %q", got, want)
+	}
+}
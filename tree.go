@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TestNode is a test together with its subtests.
+type TestNode struct {
+	Key      Key
+	Events   Events
+	Children []*TestNode
+
+	status Status // the status of the test itself from TestStorage.StatusOf
+}
+
+// Tree returns key and all its subtests from ts arranged as a tree. Subtests
+// whose parent is missing from ts are attached to their closest ancestor.
+func (ts TestStorage) Tree(key Key) *TestNode {
+	nodes := map[Key]*TestNode{
+		key: {Key: key, Events: ts.Tests[key], status: ts.StatusOf(key)},
+	}
+	subtests := ts.FindSubtests(key)
+	keys := subtests.OrderedKeys()
+	for _, k := range keys {
+		nodes[k] = &TestNode{Key: k, Events: ts.Tests[k], status: ts.StatusOf(k)}
+	}
+	for _, k := range keys {
+		parent := k.Parent()
+		for nodes[parent] == nil && parent != key {
+			parent = parent.Parent()
+		}
+		nodes[parent].Children = append(nodes[parent].Children, nodes[k])
+	}
+	return nodes[key]
+}
+
+// Keys returns the key of n and of all its subtests.
+func (n *TestNode) Keys() []Key {
+	keys := []Key{n.Key}
+	for _, c := range n.Children {
+		keys = append(keys, c.Keys()...)
+	}
+	return keys
+}
+
+// Status rolls up the status of n and its subtests. A failing or unfinished
+// subtest takes precedence over the status of the test itself, a timed out
+// one over other unfinished subtests. Subtests that are quarantined, expected
+// to fail or flaky don't.
+func (n *TestNode) Status() Status {
+	status := n.status
+	for _, c := range n.Children {
+		switch cs := c.Status(); {
+		case FailureStatuses.Any(cs):
+			return cs
+		case cs == StatusTimeout && !FailureStatuses.Any(status):
+			status = StatusTimeout
+		case cs == StatusNone && !FailureStatuses.Any(status) && status != StatusTimeout:
+			status = StatusNone
+		}
+	}
+	return status
+}
+
+// Elapsed returns the elapsed time reported by n, or the sum of its subtests
+// when n never finished.
+func (n *TestNode) Elapsed() float64 {
+	if e := n.Events.FindFirstByAction(EndingActions...); e != nil {
+		return e.Elapsed
+	}
+	var elapsed float64
+	for _, c := range n.Children {
+		elapsed += c.Elapsed()
+	}
+	return elapsed
+}
+
+// LeafCounts counts the statuses of the innermost subtests of n.
+func (n *TestNode) LeafCounts() map[Status]int {
+	counts := make(map[Status]int)
+	if len(n.Children) == 0 {
+		counts[n.status]++
+		return counts
+	}
+	for _, c := range n.Children {
+		for status, count := range c.LeafCounts() {
+			counts[status] += count
+		}
+	}
+	return counts
+}
+
+// CountsString formats the leaf counts of n, ie. "37/40 passed, 3 failed".
+func (n *TestNode) CountsString() string {
+	counts := n.LeafCounts()
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+	parts := []string{
+		fmt.Sprintf("%d/%d passed", counts[StatusPass]+counts[StatusBench], total),
+	}
+	for _, v := range []struct {
+		status Status
+		name   string
+	}{
+		{StatusFail, "failed"},
+		{StatusPanic, "panicked"},
+		{StatusRace, "raced"},
+		{StatusTimeout, "timed out"},
+		{StatusNone, "unfinished"},
+		{StatusFlaky, "flaky"},
+		{StatusQuarantined, "quarantined"},
+		{StatusXFail, "expected to fail"},
+		{StatusXPass, "unexpectedly passed"},
+		{StatusSkip, "skipped"},
+	} {
+		if counts[v.status] > 0 {
+			parts = append(parts, statusColors[v.status](fmt.Sprintf("%d %s", counts[v.status], v.name)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PrintTree prints n with its subtests indented below it. Subtests whose
+// rolled up status is not in flags.Results are only included in the counts.
+func (ts TestStorage) PrintTree(n *TestNode, flags Flags) {
+	ts.printTree(n, flags, 0)
+}
+
+func (ts TestStorage) printTree(n *TestNode, flags Flags, depth int) {
+	events := n.Events
+	if len(events.Compact()) == 0 {
+		// the test itself never reported anything but starting, ie. when it
+		// never finished, synthesize a header from its subtests.
+		events = Events{{Package: n.Key.Package, Test: n.Key.Test}}
+	}
+	status := n.status
+	suffix := ts.failureTag(n.Key, status)
+	if note := ts.Note(n.Key); note != "" {
+		suffix += "  " + statusColors[status](note)
+	}
+	if len(n.Children) > 0 {
+		counts := "  " + n.CountsString()
+		if events.FindFirstByAction(EndingActions...) == nil {
+			if elapsed := n.Elapsed(); elapsed >= 0.01 {
+				counts = "  " + timeColor(fmt.Sprintf("(%.2fs)", elapsed)) + counts
+			}
+		}
+		suffix += counts
+	}
+	events.printDetail(ts.RunContext, flags, status, depth, suffix)
+	for _, c := range n.Children {
+		if flags.Results.Any(c.Status()) {
+			ts.printTree(c, flags, depth+1)
+		}
+	}
+}
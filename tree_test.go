@@ -0,0 +1,159 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTreeResolvedStatus(t *testing.T) {
+	timeouts := loadTests(t, "timeout.json")
+	n := timeouts.Tree(Key{Package: "example.com/ex/timeout", Test: "TestParallel"})
+	if got := n.Status(); got != StatusTimeout {
+		t.Errorf("timeout: got %v, want %v", got, StatusTimeout)
+	}
+	want := map[Status]int{StatusTimeout: 1, StatusNone: 1}
+	if got := n.LeafCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("timeout: counts %v, want %v", got, want)
+	}
+
+	panics := loadTests(t, "panics.json")
+	n = panics.Tree(Key{Package: "example.com/ex/panics", Test: "TestPanics"})
+	if got := n.Status(); got != StatusPanic {
+		t.Errorf("panic: got %v, want %v", got, StatusPanic)
+	}
+
+	cascade := loadTests(t, "cascade.json")
+	cascade.Quarantine = &Quarantine{
+		Entries: []QuarantineEntry{{KeyPattern: NewKeyPattern("example.com/ex/cascade.TestParent/bad")}},
+	}
+	cascade.ResolveStatuses()
+	n = cascade.Tree(Key{Package: "example.com/ex/cascade", Test: "TestParent"})
+	if got := n.Status(); got != StatusQuarantined {
+		t.Errorf("quarantine: got %v, want %v", got, StatusQuarantined)
+	}
+	if got, want := n.CountsString(), "1/2 passed, 1 quarantined"; got != want {
+		t.Errorf("quarantine: counts %q, want %q", got, want)
+	}
+}
+
+// newTree returns the tree of TestA from tests whose last event is the given
+// action, ActionRun for tests that never finished.
+func newTree(ends map[string]Action) *TestNode {
+	tests := NewTestStorage(nil)
+	for test, action := range ends {
+		key := Key{Package: "example.com/a", Test: test}
+		for _, e := range endedEvents(key, action, 0.5) {
+			tests.Append(e)
+		}
+	}
+	tests.ResolveStatuses()
+	return tests.Tree(Key{Package: "example.com/a", Test: "TestA"})
+}
+
+// treeString formats n as "TestA(TestA/x(TestA/x/y),TestA/z)".
+func treeString(n *TestNode) string {
+	s := n.Key.Test
+	if len(n.Children) > 0 {
+		s += "("
+		for i, c := range n.Children {
+			if i > 0 {
+				s += ","
+			}
+			s += treeString(c)
+		}
+		s += ")"
+	}
+	return s
+}
+
+func TestTree(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		ends map[string]Action
+		want string
+		keys int
+	}{
+		{"single", map[string]Action{"TestA": ActionPass}, "TestA", 1},
+		{
+			"nested",
+			map[string]Action{"TestA": ActionPass, "TestA/x": ActionPass, "TestA/x/y": ActionPass, "TestA/z": ActionPass},
+			"TestA(TestA/x(TestA/x/y),TestA/z)", 4,
+		},
+		{
+			"orphan",
+			map[string]Action{"TestA": ActionPass, "TestA/x/y": ActionPass, "TestA/x/y/z": ActionPass},
+			"TestA(TestA/x/y(TestA/x/y/z))", 3,
+		},
+		{
+			"other tests left out",
+			map[string]Action{"TestA": ActionPass, "TestAB": ActionPass, "TestA/x": ActionPass},
+			"TestA(TestA/x)", 2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := newTree(tc.ends)
+			if got := treeString(n); got != tc.want {
+				t.Errorf("got %s, want %s", got, tc.want)
+			}
+			if got := len(n.Keys()); got != tc.keys {
+				t.Errorf("%d keys, want %d", got, tc.keys)
+			}
+		})
+	}
+}
+
+func TestTreeStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		ends   map[string]Action
+		status Status
+		counts string
+	}{
+		{
+			"all passed",
+			map[string]Action{"TestA": ActionPass, "TestA/x": ActionPass, "TestA/y": ActionPass},
+			StatusPass, "2/2 passed",
+		},
+		{
+			"failed subtest",
+			map[string]Action{"TestA": ActionFail, "TestA/x": ActionPass, "TestA/y": ActionFail},
+			StatusFail, "1/2 passed, 1 failed",
+		},
+		{
+			"nested failure",
+			map[string]Action{"TestA": ActionFail, "TestA/x": ActionFail, "TestA/x/y": ActionFail, "TestA/z": ActionSkip},
+			StatusFail, "0/2 passed, 1 failed, 1 skipped",
+		},
+		{
+			"unfinished subtest",
+			map[string]Action{"TestA": ActionRun, "TestA/x": ActionPass, "TestA/y": ActionRun},
+			StatusNone, "1/2 passed, 1 unfinished",
+		},
+		{
+			"passed without its subtests finishing",
+			map[string]Action{"TestA": ActionPass, "TestA/x": ActionRun},
+			StatusNone, "0/1 passed, 1 unfinished",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n := newTree(tc.ends)
+			if got := n.Status(); got != tc.status {
+				t.Errorf("status %v, want %v", got, tc.status)
+			}
+			if got := n.CountsString(); got != tc.counts {
+				t.Errorf("counts %q, want %q", got, tc.counts)
+			}
+		})
+	}
+}
+
+func TestTreeElapsed(t *testing.T) {
+	n := newTree(map[string]Action{"TestA": ActionRun, "TestA/x": ActionPass, "TestA/y": ActionFail})
+	if got := n.Elapsed(); got != 1 {
+		t.Errorf("unfinished: got %v, want the sum of the subtests 1", got)
+	}
+	n = newTree(map[string]Action{"TestA": ActionPass, "TestA/x": ActionPass})
+	if got := n.Elapsed(); got != 0.5 {
+		t.Errorf("finished: got %v, want 0.5", got)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// artifactDir returns the directory the full output of truncated tests is
+// written to, $XDG_CACHE_HOME/tgo/artifacts or the platform's equivalent
+// unless TGO_ARTIFACTS is set.
+func (f Flags) artifactDir() (string, error) {
+	if f.Artifacts != "" {
+		return f.Artifacts, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache, "tgo", "artifacts"), nil
+}
+
+var artifactNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// WriteArtifact writes the complete output of es to a new file in dir named
+// after its key and returns the path of the file. The file is only readable
+// by the user and never replaces an existing file or follows a symlink.
+func (es Events) WriteArtifact(dir string) (string, error) {
+	if len(es) == 0 {
+		return "", nil
+	}
+	key := es[0].Key()
+	name := key.Package
+	if key.Test != "" {
+		name += "." + key.Test
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, e := range es {
+		if e.Action == ActionOutput {
+			sb.WriteString(e.Output)
+		}
+	}
+	f, err := os.CreateTemp(dir, artifactNameRe.ReplaceAllString(name, "_")+".*.log")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(sb.String()); err != nil {
+		f.Close()
+		return "", err
+	}
+	return f.Name(), f.Close()
+}
+
+// isFailureMarker reports whether e is output that explains a failure and is
+// kept when output is truncated. go test marks t.Error output since go1.24.
+func isFailureMarker(e Event) bool {
+	output := strings.TrimSpace(e.Output)
+	return strings.HasPrefix(output, "panic: ") ||
+		strings.HasPrefix(output, "--- FAIL") ||
+		strings.HasPrefix(e.OutputType, "error")
+}
+
+// untypedMarkers is the number of the first and of the last file:line
+// messages kept in truncated output without marked t.Error output, any of
+// them can be the error.
+const untypedMarkers = 10
+
+// formatCount formats n with thousands separators.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// TruncateOutput keeps the first TGO_OUTPUT_HEAD and last TGO_OUTPUT_TAIL
+// lines of the rendered output of es and the failure markers in between. The
+// full output is written to an artifact file referenced by the markers that
+// replace the omitted lines, as a link when links are enabled.
+func (es Events) TruncateOutput(flags Flags, links *Linker, lines []OutputLine) []OutputLine {
+	head, tail := max(flags.OutputHead, 0), max(flags.OutputTail, 0)
+	if head+tail == 0 || len(lines) <= head+tail {
+		return lines
+	}
+
+	typed := false
+	for _, e := range es {
+		if e.OutputType != "" {
+			typed = true
+			break
+		}
+	}
+
+	keep := make(map[int]bool)
+	if !typed {
+		var refs []int
+		for i := head; i < len(lines)-tail; i++ {
+			if _, ok := ParseFileRef(lines[i].Event.Output); ok {
+				refs = append(refs, i)
+			}
+		}
+		if len(refs) > 2*untypedMarkers {
+			refs = append(refs[:untypedMarkers], refs[len(refs)-untypedMarkers:]...)
+		}
+		for _, i := range refs {
+			keep[i] = true
+		}
+	}
+
+	var path string
+	if dir, err := flags.artifactDir(); err == nil {
+		if p, err := es.WriteArtifact(dir); err == nil {
+			path = p
+		}
+	}
+
+	var (
+		result  []OutputLine
+		omitted int
+	)
+	flush := func(e Event) {
+		if omitted == 0 {
+			return
+		}
+		text := fmt.Sprintf("… %s lines omitted", formatCount(omitted))
+		if path != "" {
+			text += " (full log: " + links.LinkFile(path, 1, path) + ")"
+		}
+		result = append(result, OutputLine{Event: e, Text: timeColor(text)})
+		omitted = 0
+	}
+	for i, line := range lines {
+		if i < head || i >= len(lines)-tail || keep[i] || isFailureMarker(line.Event) {
+			flush(line.Event)
+			result = append(result, line)
+			continue
+		}
+		omitted++
+	}
+	flush(lines[len(lines)-1].Event)
+	return result
+}
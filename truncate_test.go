@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsFailureMarker(t *testing.T) {
+	for _, tc := range []struct {
+		e    Event
+		want bool
+	}{
+		{Event{Output: "--- FAIL: TestX (0.00s)\n"}, true},
+		{Event{Output: "panic: boom\n"}, true},
+		{Event{Output: "    x_test.go:12: got 1\n", OutputType: "error"}, true},
+		{Event{Output: "        want 2\n", OutputType: "error-continue"}, true},
+		{Event{Output: "    x_test.go:12: step 1\n"}, false},
+		{Event{Output: "log line\n"}, false},
+	} {
+		if got := isFailureMarker(tc.e); got != tc.want {
+			t.Errorf("isFailureMarker(%q, %q) = %v, want %v", tc.e.Output, tc.e.OutputType, got, tc.want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1000: "1,000", 1234567: "1,234,567"} {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	key := Key{Package: "example.com/ex", Test: "TestX"}
+	flags := Flags{OutputHead: 2, OutputTail: 2, Artifacts: t.TempDir()}
+	render := func(es Events) []OutputLine {
+		var lines []OutputLine
+		for _, e := range es {
+			lines = append(lines, OutputLine{Event: e, Text: strings.TrimSuffix(e.Output, "\n")})
+		}
+		return lines
+	}
+
+	t.Run("short", func(t *testing.T) {
+		es := outputEvents(key, "a", "b", "c", "d")
+		if got := renderedText(es.TruncateOutput(flags, nil, render(es))); len(got) != 4 {
+			t.Errorf("got %q, want all lines", got)
+		}
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		var es Events
+		for i := 0; i < 10; i++ {
+			es = append(es, Event{Action: ActionOutput, Package: key.Package, Test: key.Test, Output: fmt.Sprintf("    x_test.go:%d: log %d\n", i, i)})
+		}
+		es[5].OutputType = "error"
+		got := renderedText(es.TruncateOutput(flags, nil, render(es)))
+		paths, _ := filepath.Glob(filepath.Join(flags.Artifacts, "example.com_ex.TestX.*.log"))
+		if len(paths) != 1 {
+			t.Fatalf("artifacts %q, want one", paths)
+		}
+		path := paths[0]
+		want := []string{
+			"    x_test.go:0: log 0",
+			"    x_test.go:1: log 1",
+			"… 3 lines omitted (full log: " + path + ")",
+			"    x_test.go:5: log 5",
+			"… 2 lines omitted (full log: " + path + ")",
+			"    x_test.go:8: log 8",
+			"    x_test.go:9: log 9",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), "\n"); n != 10 {
+			t.Errorf("artifact has %d lines, want 10", n)
+		}
+		if fi, err := os.Stat(path); err != nil {
+			t.Error(err)
+		} else if fi.Mode().Perm() != 0o600 {
+			t.Errorf("artifact mode %v, want 0600", fi.Mode().Perm())
+		}
+	})
+
+	t.Run("untyped", func(t *testing.T) {
+		lines := []string{"head", "head"}
+		for i := 0; i < 3*untypedMarkers; i++ {
+			lines = append(lines, fmt.Sprintf("    x_test.go:%d: log", i), "plain")
+		}
+		lines = append(lines, "tail", "tail")
+		es := outputEvents(key, lines...)
+		kept := 0
+		for _, text := range renderedText(es.TruncateOutput(flags, nil, render(es))) {
+			if _, ok := ParseFileRef(text); ok {
+				kept++
+			}
+		}
+		if kept != 2*untypedMarkers {
+			t.Errorf("kept %d file:line lines, want %d", kept, 2*untypedMarkers)
+		}
+	})
+}
+
+func TestWriteArtifact(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "artifacts")
+	es := outputEvents(Key{Package: "example.com/ex", Test: "TestX"}, "a", "b")
+	first, err := es.WriteArtifact(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := es.WriteArtifact(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("both artifacts written to %s", first)
+	}
+	if fi, err := os.Stat(dir); err != nil {
+		t.Error(err)
+	} else if fi.Mode().Perm() != 0o700 {
+		t.Errorf("artifact directory mode %v, want 0700", fi.Mode().Perm())
+	}
+}
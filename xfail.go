@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// XFail is a line of an xfail file.
+type XFail struct {
+	KeyPattern
+	Message string // the failure message regexp, "" matches any failure
+	message *regexp.Regexp
+}
+
+// MatchFailure reports whether the failure of events is the expected one.
+func (x XFail) MatchFailure(events Events) bool {
+	return x.message == nil || x.message.MatchString(events.FailureMessage())
+}
+
+// XFails is a list of tests documenting known bugs that are expected to fail,
+// read from a file with a key pattern per line optionally followed by a
+// regexp the failure message must match:
+//
+//	# pattern                        message
+//	example.com/pkg.TestIssue123     want 3, got 2
+//	example.com/pkg.TestIssue456/*
+type XFails struct {
+	Filename string
+	Entries  []XFail
+}
+
+// ReadXFails reads the xfail file filename.
+func ReadXFails(filename string) (*XFails, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	xs := &XFails{Filename: filename}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern, message := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			pattern, message = line[:i], strings.TrimSpace(line[i:])
+		}
+		x := XFail{KeyPattern: NewKeyPattern(pattern), Message: message}
+		if x.Message != "" {
+			if x.message, err = regexp.Compile(x.Message); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid message regexp: %w", filename, n, err)
+			}
+		}
+		xs.Entries = append(xs.Entries, x)
+	}
+	return xs, scanner.Err()
+}
+
+// FindFailure returns the entry expecting the failure of key with events, nil
+// if it isn't expected. It is safe to call on a nil XFails.
+func (xs *XFails) FindFailure(key Key, events Events) *XFail {
+	if xs == nil {
+		return nil
+	}
+	for i, x := range xs.Entries {
+		if x.Match(key) && x.MatchFailure(events) {
+			return &xs.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Find returns the entry declaring key itself, not one of its parents, as
+// expected to fail, nil if there is none. It is safe to call on a nil XFails.
+func (xs *XFails) Find(key Key) *XFail {
+	if xs == nil {
+		return nil
+	}
+	for i, x := range xs.Entries {
+		if x.KeyPattern.re.MatchString(key.String()) {
+			return &xs.Entries[i]
+		}
+	}
+	return nil
+}
+
+// expectedFailure returns StatusQuarantined or StatusXFail when the failed key
+// has no failure of its own but tests below it that are quarantined or
+// expected to fail, "" otherwise. Quarantined wins when there are both.
+func (ts TestStorage) expectedFailure(key Key) Status {
+	if ts.Quarantine == nil && ts.XFails == nil {
+		return ""
+	}
+	below := ts.FindSubtests(key)
+	if key.Test == "" {
+		below = ts.FindPackageTests(key.Package)
+	} else if ts.Tests[key].HasOwnOutput() {
+		return ""
+	}
+	var status Status
+	for k := range below.Tests {
+		if k == key {
+			continue
+		}
+		switch s := ts.StatusOf(k); s {
+		case StatusQuarantined:
+			status = s
+		case StatusXFail:
+			if status == "" {
+				status = s
+			}
+		case StatusFail, StatusPanic, StatusRace, StatusTimeout, StatusNone:
+			return ""
+		}
+	}
+	return status
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadXFails(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		want    []XFail
+		err     string
+	}{
+		{
+			name: "entries",
+			content: "# pattern message\n\n" +
+				"example.com/a.TestIssue123  want 3, got 2\n" +
+				"example.com/a.TestIssue456/*\n" +
+				"example.com/a.TestTab\tboom\n",
+			want: []XFail{
+				{KeyPattern: NewKeyPattern("example.com/a.TestIssue123"), Message: "want 3, got 2"},
+				{KeyPattern: NewKeyPattern("example.com/a.TestIssue456/*")},
+				{KeyPattern: NewKeyPattern("example.com/a.TestTab"), Message: "boom"},
+			},
+		},
+		{
+			name:    "invalid message",
+			content: "# header\nexample.com/a.TestX  want (\n",
+			err:     ":2: invalid message regexp",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			xs, err := ReadXFails(writeFile(t, ".tgo-xfail", tc.content))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []XFail
+			for _, x := range xs.Entries {
+				got = append(got, XFail{KeyPattern: NewKeyPattern(x.Pattern), Message: x.Message})
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestXFailsFind(t *testing.T) {
+	xs := &XFails{Entries: []XFail{{KeyPattern: NewKeyPattern("example.com/a.TestX")}}}
+	for _, tc := range []struct {
+		key  Key
+		want bool
+	}{
+		{Key{Package: "example.com/a", Test: "TestX"}, true},
+		{Key{Package: "example.com/a", Test: "TestX/sub"}, false},
+		{Key{Package: "example.com/a", Test: "TestY"}, false},
+	} {
+		if got := xs.Find(tc.key) != nil; got != tc.want {
+			t.Errorf("Find(%v) = %v, want %v", tc.key, got, tc.want)
+		}
+	}
+	var none *XFails
+	if none.Find(Key{Package: "example.com/a", Test: "TestX"}) != nil {
+		t.Error("nil XFails found an entry")
+	}
+}
+
+func TestXFailStatus(t *testing.T) {
+	xs, err := ReadXFails(writeFile(t, ".tgo-xfail",
+		"example.com/a.TestBug  want 3\n"+
+			"example.com/a.TestOther  want 3\n"+
+			"example.com/a.TestFixed\n"+
+			"example.com/a.TestParent/bad\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := NewTestStorage(&RunContext{XFails: xs})
+	pkg := "example.com/a"
+	for _, es := range []Events{
+		endedEvents(Key{Package: pkg, Test: "TestBug"}, ActionFail, 0, "    a_test.go:1: want 3, got 2"),
+		endedEvents(Key{Package: pkg, Test: "TestOther"}, ActionFail, 0, "    a_test.go:1: boom"),
+		endedEvents(Key{Package: pkg, Test: "TestFixed"}, ActionPass, 0),
+		endedEvents(Key{Package: pkg, Test: "TestParent/bad"}, ActionFail, 0, "    a_test.go:9: bad"),
+		endedEvents(Key{Package: pkg, Test: "TestParent"}, ActionFail, 0),
+	} {
+		for _, e := range es {
+			tests.Append(e)
+		}
+	}
+	tests.ResolveStatuses()
+	for _, tc := range []struct {
+		test string
+		want Status
+	}{
+		{"TestBug", StatusXFail},
+		{"TestOther", StatusFail},
+		{"TestFixed", StatusXPass},
+		{"TestParent/bad", StatusXFail},
+		{"TestParent", StatusXFail},
+	} {
+		key := Key{Package: pkg, Test: tc.test}
+		if got := tests.StatusOf(key); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.test, got, tc.want)
+		}
+	}
+}
+
+func TestOnlyExpectedFailuresXFail(t *testing.T) {
+	xs := &XFails{Entries: []XFail{{KeyPattern: NewKeyPattern("example.com/a.TestBug")}}}
+	// go test reports a usage error without any events
+	tests := NewTestStorage(&RunContext{XFails: xs})
+	tests.ResolveStatuses()
+	if tests.OnlyExpectedFailures(Flags{}) {
+		t.Error("usage error: got true, want false")
+	}
+
+	pkg := "example.com/a"
+	for _, es := range []Events{
+		endedEvents(Key{Package: pkg, Test: "TestBug"}, ActionFail, 0, "    a_test.go:1: bad"),
+		endedEvents(Key{Package: pkg}, ActionFail, 0),
+	} {
+		for _, e := range es {
+			tests.Append(e)
+		}
+	}
+	tests.ResolveStatuses()
+	if !tests.OnlyExpectedFailures(Flags{}) {
+		t.Error("expected failure: got false, want true")
+	}
+}